@@ -124,7 +124,6 @@ func mapValueType(field reflect.Value, val string) error {
 	default:
 		return errors.New("Unknown type")
 	}
-	return nil
 }
 func setInt(s string, bitSize int, field reflect.Value) error {
 	i, err := strconv.ParseInt(s, 10, bitSize)
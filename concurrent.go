@@ -0,0 +1,115 @@
+package gov
+
+// routeTable is the routing state a router with concurrent routes serves
+// requests with. It is never modified once published.
+type routeTable struct {
+	trees     methodTrees
+	maxParams int
+}
+
+// EnableConcurrentRoutes makes it safe to add and remove routes of r while
+// it serves requests, e.g. for endpoints loaded by plugins or behind feature
+// flags. A change copies the tree of its method, modifies the copy and
+// publishes it atomically: requests are served without locking and never
+// see a partially registered route. Changes are serialized and copy a whole
+// tree, so they are meant to be occasional.
+//
+// It must be called before r serves requests. Middlewares and hosts are not
+// covered and still have to be set up beforehand.
+func (r *Router) EnableConcurrentRoutes() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.concurrent = true
+	r.publish()
+}
+
+func (r *Router) publish() {
+	r.live.Store(&routeTable{trees: r.trees, maxParams: r.maxParams})
+}
+
+// currentTrees returns the trees requests are served with.
+func (r *Router) currentTrees() methodTrees {
+	if r.concurrent {
+		return r.live.Load().(*routeTable).trees
+	}
+	return r.trees
+}
+
+func (r *Router) currentMaxParams() int {
+	if r.concurrent {
+		return r.live.Load().(*routeTable).maxParams
+	}
+	return r.maxParams
+}
+
+// Remove removes the route registered for method and path, written as it
+// was registered, e.g. "/users/:id<int>". It reports whether there was such
+// a route. Names given to the route are kept, so registering the path again
+// makes them usable again.
+func (r *Router) Remove(method, path string) bool {
+	if r.concurrent {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+
+	old := r.trees.get(method)
+	if old == nil {
+		return false
+	}
+
+	found := false
+	var kept []registration
+	old.walk(func(n *node) {
+		if n.handlers == nil {
+			return
+		}
+		if n.fullPath == path {
+			found = true
+			return
+		}
+		kept = append(kept, registration{path: n.fullPath, handlers: n.handlers, site: n.site})
+	})
+	if !found {
+		return false
+	}
+
+	// rebuild the tree so that no wildcard introduced by the route remains
+	trees := make(methodTrees, 0, len(r.trees))
+	for _, t := range r.trees {
+		if t.method != method {
+			trees = append(trees, t)
+			continue
+		}
+		if len(kept) == 0 {
+			continue
+		}
+
+		root := new(node)
+		for _, reg := range kept {
+			if err := root.addRoute(reg.path, reg.handlers, reg.site); err != nil {
+				panic(err)
+			}
+		}
+		trees = append(trees, methodTree{method: method, root: root})
+	}
+
+	r.trees = trees
+	if r.concurrent {
+		r.publish()
+	}
+	return true
+}
+
+// clone returns a copy of trees in which the tree of method is a deep copy,
+// so that it can be modified without affecting trees.
+func (trees methodTrees) clone(method string) methodTrees {
+	c := make(methodTrees, len(trees), len(trees)+1)
+	copy(c, trees)
+	for i := range c {
+		if c[i].method == method {
+			c[i].root = c[i].root.clone()
+		}
+	}
+	return c
+}
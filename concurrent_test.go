@@ -0,0 +1,98 @@
+package gov
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterRemove(t *testing.T) {
+	r := New()
+	r.Get("/users/:id<int>", func(c *Context) { c.String("int") })
+	r.Get("/users/:name", func(c *Context) { c.String("name") })
+	r.Get("/files/*path", func(c *Context) {})
+	r.Post("/users", func(c *Context) {})
+
+	assert.False(t, r.Remove("GET", "/users/:id"))
+	assert.False(t, r.Remove("PUT", "/users"))
+
+	assert.True(t, r.Remove("GET", "/users/:id<int>"))
+	assert.Equal(t, "name\n", performRequest(r, "GET", "/users/42").Body.String())
+
+	assert.True(t, r.Remove("GET", "/users/:name"))
+	assert.Equal(t, http.StatusMethodNotAllowed, performRequest(r, "GET", "/users").Code)
+	assert.Equal(t, http.StatusNotFound, performRequest(r, "GET", "/users/42").Code)
+
+	// the removed wildcard no longer conflicts
+	r.Get("/users/:uid/posts", func(c *Context) { c.String(c.Param("uid").(string)) })
+	assert.Equal(t, "7\n", performRequest(r, "GET", "/users/7/posts").Body.String())
+
+	assert.True(t, r.Remove("POST", "/users"))
+	for _, route := range r.Routes() {
+		assert.NotEqual(t, "POST", route.Method)
+	}
+}
+
+func TestConcurrentRoutesRegistrationError(t *testing.T) {
+	r := New()
+	r.EnableConcurrentRoutes()
+	r.Get("/users/:id", func(c *Context) {})
+
+	assert.Error(t, r.Handle("GET", "/users/:name", func(c *Context) {}))
+	assert.NoError(t, r.Handle("GET", "/users/:id/posts", func(c *Context) {}))
+	assert.Len(t, r.Routes(), 2)
+}
+
+func TestConcurrentRoutesUnderLoad(t *testing.T) {
+	r := New()
+	r.EnableConcurrentRoutes()
+	r.Get("/health", func(c *Context) { c.Status(http.StatusNoContent) })
+	users := r.Group("/users", func(c *Context) {})
+
+	var stop int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; atomic.LoadInt32(&stop) == 0; n++ {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+				if w.Code != http.StatusNoContent {
+					t.Errorf("GET /health: got %d", w.Code)
+					return
+				}
+
+				w = httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/users/"+strconv.Itoa(n%100)+"/item/"+strconv.Itoa(i), nil))
+				if w.Code != http.StatusOK && w.Code != http.StatusNotFound {
+					t.Errorf("GET /users: got %d", w.Code)
+					return
+				}
+			}
+		}(i)
+	}
+
+	for n := 0; n < 100; n++ {
+		path := "/" + strconv.Itoa(n) + "/item/:id"
+		users.Get(path, func(c *Context) { c.String(c.Param("id").(string)) }).Name("item" + strconv.Itoa(n))
+
+		url, err := r.URLFor("item"+strconv.Itoa(n), "id", "x")
+		assert.NoError(t, err)
+		assert.Equal(t, "x\n", performRequest(r, "GET", url).Body.String())
+
+		if n%3 == 0 {
+			assert.True(t, r.Remove("GET", "/users"+path))
+			assert.Equal(t, http.StatusNotFound, performRequest(r, "GET", url).Code)
+		}
+	}
+
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	assert.Len(t, r.Routes(), 1+100-34)
+}
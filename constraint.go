@@ -0,0 +1,135 @@
+package gov
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// paramConstraints are the named constraints usable as ":name<int>". Any
+// other constraint is compiled as a regular expression that has to match
+// the whole segment. A param captures a single segment, so a constraint
+// requiring a slash could never match and is rejected.
+var paramConstraints = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isDigits,
+	"alpha": isAlpha,
+	"uuid":  isUUID,
+}
+
+// compileConstraint returns the check for the constraint of a param.
+func compileConstraint(constraint string) (func(string) bool, error) {
+	if check, ok := paramConstraints[constraint]; ok {
+		return check, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := syntax.Parse(constraint, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	if hasSlash(parsed) {
+		return nil, errors.New("a param matches a single segment, which has no '/'")
+	}
+	return re.MatchString, nil
+}
+
+// hasSlash reports whether the regular expression contains a literal slash.
+func hasSlash(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpLiteral {
+		for _, r := range re.Rune {
+			if r == '/' {
+				return true
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		if hasSlash(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardEnd returns the length of the param segment at the start of path.
+// The slashes inside a constraint, as in ":tag<[^/]+>", do not end it.
+func wildcardEnd(path string) int {
+	depth := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '<':
+			depth++
+		case '>':
+			if depth > 0 {
+				depth--
+			}
+		case '/':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(path)
+}
+
+// splitParam splits a param segment like ":id<int>" into its name and
+// constraint.
+func splitParam(seg string) (name, constraint string) {
+	i := strings.IndexByte(seg, '<')
+	if i < 0 || seg[len(seg)-1] != '>' {
+		return seg[1:], ""
+	}
+	return seg[1:i], seg[i+1 : len(seg)-1]
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isInt(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isDigits(s)
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isUUID reports whether s is a UUID in its canonical 8-4-4-4-12 form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,11 +1,15 @@
 package gov
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,16 +23,66 @@ type Param struct {
 
 type Params []Param
 
+// ErrParamNotFound is returned by the typed param accessors when the route
+// has no such param.
+var ErrParamNotFound = errors.New("gov: param not found")
+
+// Get returns the value of the first param with the given key.
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// abortIndex is past the end of any handler chain, so setting the index to
+// it stops Next from calling further handlers.
+const abortIndex int = math.MaxInt32
+
 type Context struct {
 	Request    *http.Request
 	Response   http.ResponseWriter
+	writer     responseWriter
+	handlers   HandlerChain
+	index      int
 	params     Params
 	queryCache url.Values
 	formCache  url.Values
 	Storage    map[string]interface{}
 }
 
+// Copy returns a copy of the context that can be used outside the request
+// scope, e.g. in a goroutine. The Context passed to handlers is reused for
+// later requests once the handlers return, so handlers must not keep it.
+// The copy carries the request, params and storage but cannot write the
+// response nor run the handler chain.
+func (c *Context) Copy() *Context {
+	cp := Context{
+		Request:    c.Request,
+		index:      abortIndex,
+		queryCache: c.queryCache,
+		formCache:  c.formCache,
+	}
+	cp.writer.reset(nil)
+	cp.Response = &cp.writer
+
+	cp.params = make(Params, len(c.params))
+	copy(cp.params, c.params)
+
+	if c.Storage != nil {
+		cp.Storage = make(map[string]interface{}, len(c.Storage))
+		for k, v := range c.Storage {
+			cp.Storage[k] = v
+		}
+	}
+	return &cp
+}
+
 func (c *Context) reset() {
+	c.handlers = c.handlers[0:0]
+	c.index = -1
 	c.params = c.params[0:0]
 	c.Storage = nil
 	c.formCache = nil
@@ -36,7 +90,44 @@ func (c *Context) reset() {
 }
 
 func (c *Context) resetWriter(w http.ResponseWriter) {
-	c.Response = w
+	c.writer.reset(w)
+	c.Response = &c.writer
+}
+
+// flow control
+
+// Next runs the pending handlers of the chain. Middlewares call it to run
+// code after the downstream handlers have completed.
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort prevents pending handlers from being called. It does not stop the
+// current handler.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus aborts the chain and writes the status code.
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.writer.WriteHeaderNow()
+	c.Abort()
+}
+
+// AbortWithStatusJSON aborts the chain and writes obj as the JSON body.
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.Status(code)
+	c.Json(obj)
 }
 
 func (c *Context) Path() string {
@@ -63,6 +154,23 @@ func (c *Context) header(key string) string {
 	return c.Request.Header.Get(key)
 }
 
+// PeerCertificate returns the client certificate verified during the TLS
+// handshake, or nil if the connection is not over TLS or the client sent no
+// certificate verified against the ClientCAs of the TLS configuration.
+func (c *Context) PeerCertificate() *x509.Certificate {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return c.Request.TLS.VerifiedChains[0][0]
+}
+
+// Proto returns the protocol version of the request, "HTTP/1.1" or
+// "HTTP/2.0" for instance. The HTTP/1.1 request upgrading a connection to
+// h2c keeps its version, the following ones on the connection are HTTP/2.
+func (c *Context) Proto() string {
+	return c.Request.Proto
+}
+
 func (c *Context) Json(resp_body interface{}) {
 	writeContentType(c.Response, []string{"application/json; charset=utf-8"})
 	r, err := json.Marshal(resp_body)
@@ -179,15 +287,55 @@ func (c *Context) GetStringMap(key string) (sm map[string]interface{}) {
 // Parse params、query、form
 
 func (c *Context) Param(key string) interface{} {
-	for _, p := range c.params {
-		if p.Key == key {
-			return p.Value
-		}
+	if value, ok := c.params.Get(key); ok {
+		return value
 	}
 
 	return nil
 }
 
+func (c *Context) ParamString(key string) (string, error) {
+	if value, ok := c.params.Get(key); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrParamNotFound, key)
+}
+
+func (c *Context) ParamInt(key string) (int, error) {
+	i, err := c.ParamInt64(key)
+	if err == nil && int64(int(i)) != i {
+		return 0, fmt.Errorf("gov: param %q: %d overflows int", key, i)
+	}
+	return int(i), err
+}
+
+func (c *Context) ParamInt64(key string) (int64, error) {
+	value, err := c.ParamString(key)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("gov: param %q: %w", key, err)
+	}
+	return i, nil
+}
+
+// ParamUUID returns the param as a lower-case UUID in its canonical
+// 8-4-4-4-12 form.
+func (c *Context) ParamUUID(key string) (string, error) {
+	value, err := c.ParamString(key)
+	if err != nil {
+		return "", err
+	}
+
+	if !isUUID(value) {
+		return "", fmt.Errorf("gov: param %q: %q is not a UUID", key, value)
+	}
+	return strings.ToLower(value), nil
+}
+
 func (c *Context) QueryOr(key string, defaultValue string) string {
 	if value, ok := c.GetQuery(key); ok {
 		return value
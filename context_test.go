@@ -2,6 +2,7 @@ package gov
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -227,3 +228,148 @@ func TestContextWriteAttachement(t *testing.T) {
 type Foo struct {
 	Bar string
 }
+
+func TestContextNextAndAbort(t *testing.T) {
+	r := New()
+	trace := ""
+
+	r.Use(func(c *Context) {
+		trace += "timing:before "
+		c.Next()
+		trace += "timing:after"
+	})
+	r.Use(func(c *Context) {
+		if c.Query("token") == "" {
+			trace += "auth:reject "
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		trace += "auth:ok "
+	})
+	r.Get("/secret", func(c *Context) {
+		assert.False(t, c.IsAborted())
+		trace += "handler "
+	})
+
+	w := performRequest(r, "GET", "/secret")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "timing:before auth:reject timing:after", trace)
+
+	trace = ""
+	w = performRequest(r, "GET", "/secret?token=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "timing:before auth:ok handler timing:after", trace)
+
+	trace = ""
+	w = performRequest(r, "GET", "/missing?token=1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "timing:before auth:ok timing:after", trace)
+}
+
+func TestContextAbortWithStatusJSON(t *testing.T) {
+	r := New()
+	called := false
+
+	g := r.Group("/api", func(c *Context) {
+		c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+		assert.True(t, c.IsAborted())
+	})
+	g.Get("/users", func(c *Context) { called = true })
+
+	w := performRequest(r, "GET", "/api/users")
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"error\":\"forbidden\"}\n", w.Body.String())
+}
+
+func TestContextCopy(t *testing.T) {
+	r := New()
+	var copied *Context
+
+	r.Get("/users/:id", func(c *Context) {
+		c.Set("user", c.Param("id"))
+		if copied == nil {
+			copied = c.Copy()
+		}
+	})
+
+	performRequest(r, "GET", "/users/1")
+	performRequest(r, "GET", "/users/2")
+
+	assert.Equal(t, "1", copied.Param("id"))
+	assert.Equal(t, "1", copied.GetString("user"))
+	assert.Equal(t, "/users/1", copied.Path())
+	assert.True(t, copied.IsAborted())
+}
+
+func TestContextPoolReset(t *testing.T) {
+	r := New()
+	r.Get("/set", func(c *Context) {
+		c.Set("key", "value")
+		c.Query("q")
+	})
+	r.Get("/get", func(c *Context) {
+		_, exists := c.Get("key")
+		assert.False(t, exists)
+		assert.Empty(t, c.Query("q"))
+		assert.Nil(t, c.Param("id"))
+	})
+
+	for i := 0; i < 10; i++ {
+		performRequest(r, "GET", "/set?q=1")
+		performRequest(r, "GET", "/get")
+	}
+}
+
+func TestContextTypedParams(t *testing.T) {
+	c, _ := CrateTestCtx(nil)
+	c.params = Params{
+		{"id", "42"},
+		{"big", "9223372036854775807"},
+		{"name", "gopher"},
+		{"uuid", "3F2504E0-4F89-11D3-9A0C-0305E82C3301"},
+	}
+
+	s, err := c.ParamString("name")
+	assert.NoError(t, err)
+	assert.Equal(t, "gopher", s)
+
+	i, err := c.ParamInt("id")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, i)
+
+	i64, err := c.ParamInt64("big")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9223372036854775807), i64)
+
+	u, err := c.ParamUUID("uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, "3f2504e0-4f89-11d3-9a0c-0305e82c3301", u)
+
+	_, err = c.ParamInt("name")
+	assert.Error(t, err)
+
+	_, err = c.ParamUUID("name")
+	assert.Error(t, err)
+
+	_, err = c.ParamString("missing")
+	assert.True(t, errors.Is(err, ErrParamNotFound))
+
+	_, err = c.ParamInt64("missing")
+	assert.True(t, errors.Is(err, ErrParamNotFound))
+}
+
+func TestContextHijackUnsupported(t *testing.T) {
+	r := New()
+	r.Get("/ws", func(c *Context) {
+		_, _, err := c.Response.(http.Hijacker).Hijack()
+		assert.Error(t, err)
+		assert.False(t, c.writer.Written())
+		c.Status(http.StatusNotImplemented)
+	})
+
+	// a recorder cannot be hijacked
+	w := performRequest(r, "GET", "/ws")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
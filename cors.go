@@ -0,0 +1,152 @@
+package gov
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins. "*" allows any origin and an
+	// origin may contain one wildcard, e.g. "https://*.example.com".
+	AllowOrigins []string
+
+	// AllowOriginFunc allows the origins it returns true for, in addition
+	// to AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods lists the methods allowed in preflight responses. When
+	// empty, the Allow header of the automatic OPTIONS response is used, or
+	// the requested method if there is none.
+	AllowMethods []string
+
+	// AllowHeaders lists the request headers allowed in preflight
+	// responses. When empty, the requested headers are allowed.
+	AllowHeaders []string
+
+	// ExposeHeaders lists the response headers readable by the client.
+	ExposeHeaders []string
+
+	// AllowCredentials allows cookies and HTTP authentication. It cannot be
+	// combined with the "*" origin, the allowed origins have to be listed or
+	// checked by AllowOriginFunc.
+	AllowCredentials bool
+
+	// MaxAge is how long the result of a preflight request may be cached.
+	MaxAge time.Duration
+}
+
+// CORS returns a middleware handling cross-origin requests. Preflight
+// requests are answered with 204, or 403 if the origin is not allowed, and
+// do not reach the route handlers.
+//
+// Register it with Gov.Use or on a host router so that it also runs for the
+// OPTIONS requests answered by HandleOPTIONS; middlewares of a group only
+// run for the routes of the group. It panics if AllowCredentials is set with
+// the "*" origin, which would let any site make credentialed requests.
+func CORS(config CORSConfig) HandlerFunc {
+	anyOrigin := false
+	exact := make(map[string]bool)
+	var wildcards [][2]string
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			anyOrigin = true
+		case i >= 0:
+			wildcards = append(wildcards, [2]string{origin[:i], origin[i+1:]})
+		default:
+			exact[origin] = true
+		}
+	}
+
+	if anyOrigin && config.AllowCredentials {
+		panic("CORS origin \"*\" cannot be used with AllowCredentials")
+	}
+
+	allowed := func(origin string) bool {
+		if anyOrigin {
+			return true
+		}
+
+		lower := strings.ToLower(origin)
+		if exact[lower] {
+			return true
+		}
+		for _, w := range wildcards {
+			if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+				return true
+			}
+		}
+		return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+	}
+
+	methods := strings.Join(config.AllowMethods, ", ")
+	headers := strings.Join(config.AllowHeaders, ", ")
+	expose := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := ""
+	if config.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+
+	return func(c *Context) {
+		origin := c.Header("Origin")
+		if origin == "" {
+			return
+		}
+
+		h := c.Response.Header()
+		preflight := c.Method() == http.MethodOptions && c.Header("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Origin")
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		} else if !anyOrigin {
+			h.Add("Vary", "Origin")
+		}
+
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+			return
+		}
+
+		if anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if expose != "" {
+				h.Set("Access-Control-Expose-Headers", expose)
+			}
+			return
+		}
+
+		allowMethods := methods
+		if allowMethods == "" {
+			if allowMethods = h.Get("Allow"); allowMethods == "" {
+				allowMethods = c.Header("Access-Control-Request-Method")
+			}
+		}
+		h.Set("Access-Control-Allow-Methods", allowMethods)
+
+		if headers != "" {
+			h.Set("Access-Control-Allow-Headers", headers)
+		} else if requested := c.Header("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
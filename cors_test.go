@@ -0,0 +1,117 @@
+package gov
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performCORSRequest(r http.Handler, method, path, origin string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Origin", origin)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSConfig{
+		AllowOrigins:  []string{"https://app.example.com", "https://*.example.org"},
+		MaxAge:        10 * time.Minute,
+		ExposeHeaders: []string{"X-Total"},
+	}))
+	called := false
+	r.Put("/items/:id", func(c *Context) { called = true })
+
+	w := performCORSRequest(r, "OPTIONS", "/items/1", "https://app.example.com",
+		"Access-Control-Request-Method", "PUT",
+		"Access-Control-Request-Headers", "Content-Type")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "PUT, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Contains(t, w.Header()["Vary"], "Origin")
+	assert.False(t, called)
+
+	w = performCORSRequest(r, "OPTIONS", "/items/1", "https://eu.example.org",
+		"Access-Control-Request-Method", "PUT")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://eu.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = performCORSRequest(r, "OPTIONS", "/items/1", "https://.example.org",
+		"Access-Control-Request-Method", "PUT")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = performCORSRequest(r, "OPTIONS", "/items/1", "https://evil.com",
+		"Access-Control-Request-Method", "PUT")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCORSRequest(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSConfig{
+		AllowOriginFunc:  func(origin string) bool { return origin == "http://localhost:3000" },
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Authorization"},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"X-Total", "X-Page"},
+	}))
+	r.Get("/items", func(c *Context) { c.String("items") })
+
+	w := performCORSRequest(r, "GET", "/items", "http://localhost:3000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "items\n", w.Body.String())
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Total, X-Page", w.Header().Get("Access-Control-Expose-Headers"))
+
+	w = performCORSRequest(r, "OPTIONS", "/items", "http://localhost:3000",
+		"Access-Control-Request-Method", "POST",
+		"Access-Control-Request-Headers", "X-Custom")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+
+	w = performCORSRequest(r, "GET", "/items", "http://other.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = performRequest(r, "GET", "/items")
+	assert.Empty(t, w.Header().Get("Vary"))
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSConfig{AllowOrigins: []string{"*"}}))
+	r.Get("/items", func(c *Context) {})
+
+	w := performCORSRequest(r, "GET", "/items", "https://anywhere.io")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Vary"))
+
+	w = performCORSRequest(r, "OPTIONS", "/items", "https://anywhere.io",
+		"Access-Control-Request-Method", "GET")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func TestCORSAnyOriginWithCredentials(t *testing.T) {
+	assert.Panics(t, func() {
+		CORS(CORSConfig{AllowOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true})
+	})
+
+	// a predicate allowing the origins is explicit
+	assert.NotPanics(t, func() {
+		CORS(CORSConfig{AllowOriginFunc: func(string) bool { return true }, AllowCredentials: true})
+	})
+}
@@ -1,162 +1,308 @@
 package gov
 
 import (
-	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+)
+
+const (
+	default404Body = "404 page not found"
+	default405Body = "405 method not allowed"
 )
 
 type Gov struct {
 	Router
+
+	// HandleMethodNotAllowed answers requests whose path is only registered
+	// for other methods with 405 and an Allow header instead of 404.
+	HandleMethodNotAllowed bool
+
+	// RedirectTrailingSlash redirects "/users/" to "/users" and the other
+	// way round when only the alternate form has a route.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath redirects paths like "//users/../users" to their
+	// cleaned form when it has a route.
+	RedirectFixedPath bool
+
+	// HandleHEAD answers HEAD requests with the GET route of the path when
+	// there is no HEAD route. The body is discarded, the headers and the
+	// Content-Length are kept.
+	HandleHEAD bool
+
+	// HandleOPTIONS answers OPTIONS requests for paths without an OPTIONS
+	// route with 204 and an Allow header listing the methods of the path.
+	// The global middlewares run first, so a CORS middleware registered with
+	// Use can answer preflight requests. "OPTIONS *" is answered by
+	// net/http before reaching Gov.
+	HandleOPTIONS bool
+
+	// H2C serves HTTP/2 over cleartext connections to clients starting with
+	// the HTTP/2 preface (prior knowledge) or asking to upgrade an HTTP/1.1
+	// request, besides HTTP/1.1. TLS connections negotiate HTTP/2 anyway.
+	// Running with H2C set wraps the Server handler, which then refuses new
+	// h2c connections while H2C is cleared.
+	H2C bool
+
+	noRoute  HandlerChain
+	noMethod HandlerChain
+
+	hosts []*hostRouter
+
+	// Server is the server Run and its variants listen with. Its timeouts
+	// and limits can be set before running; Handler defaults to Gov.
+	Server *http.Server
+
+	h2cConns sync.WaitGroup
+
+	onShutdown   []func()
+	shutdownOnce sync.Once
+
+	pool sync.Pool
 }
 
 func New() *Gov {
-	return &Gov{
+	v := &Gov{
 		Router: Router{
 			trees:       make(methodTrees, 0, 9),
 			Middlewares: make(HandlerChain, 0),
 		},
+		HandleMethodNotAllowed: true,
+		RedirectTrailingSlash:  true,
+		HandleHEAD:             true,
+		HandleOPTIONS:          true,
+	}
+	v.Server = &http.Server{Handler: v}
+	v.pool.New = func() interface{} {
+		return v.allocateContext()
 	}
+	return v
+}
+
+// NoRoute sets the handlers for requests that match no route. The response
+// status is 404 unless the handlers set another one.
+func (v *Gov) NoRoute(handlers ...HandlerFunc) {
+	v.noRoute = handlers
+}
+
+// NoMethod sets the handlers for requests whose path matches a route of
+// another method. The response status is 405 unless the handlers set
+// another one. It requires HandleMethodNotAllowed.
+func (v *Gov) NoMethod(handlers ...HandlerFunc) {
+	v.noMethod = handlers
 }
 
 func (v *Gov) allocateContext() *Context {
-	return &Context{}
+	maxParams := v.Router.currentMaxParams()
+	for _, h := range v.hosts {
+		if n := h.maxParams(); n > maxParams {
+			maxParams = n
+		}
+	}
+	return &Context{params: make(Params, 0, maxParams)}
 }
 
+// ServeHTTP takes a Context from the pool and puts it back once the handlers
+// return, so handlers must not keep it (see Context.Copy).
 func (v *Gov) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	c := &Context{
-		Request:  r,
-		Response: w,
-	}
+	c := v.pool.Get().(*Context)
+	c.Request = r
+	c.reset()
+	c.resetWriter(w)
 
 	v.handleHTTPRequest(c)
-}
-
-func (v *Gov) Run(addr ...string) error {
-	address := resolveAddr(addr)
-	fmt.Println("[gov] listening on " + address)
 
-	err := http.ListenAndServe(address, v)
-	return err
+	c.Request = nil
+	c.resetWriter(nil)
+	v.pool.Put(c)
 }
 
-func resolveAddr(addr []string) string {
-	switch len(addr) {
-	case 0:
-		return ":9000"
-	case 1:
-		return addr[0]
-	default:
-		panic("too many parameters")
+func (v *Gov) handleHTTPRequest(c *Context) {
+	router := &v.Router
+	if len(v.hosts) > 0 {
+		router = v.routerForHost(c)
 	}
-}
 
-func (v *Gov) handleHTTPRequest(c *Context) {
+	method, path := c.Method(), c.Path()
+	n := router.handle(method, path, c.params)
 
-	for _, m := range v.Router.Middlewares {
-		m(c)
+	if n.handlers == nil && method == http.MethodHead && v.HandleHEAD {
+		if n = router.handle(http.MethodGet, path, c.params); n.handlers != nil {
+			hw := &headWriter{ResponseWriter: c.writer.ResponseWriter}
+			c.writer.ResponseWriter = hw
+			defer hw.finish()
+		}
 	}
 
-	path := c.Path()
-	m := c.Method()
+	if n.handlers != nil {
+		c.params = n.params
+		v.setChain(c, router, n.handlers)
+		c.Next()
+		c.writer.WriteHeaderNow()
+		return
+	}
 
-	n := v.handle(m, path)
+	if method == http.MethodOptions && v.HandleOPTIONS {
+		if allow := v.allowed(router, method, path); allow != "" {
+			c.SetHeader("Allow", allow)
+			v.setChain(c, router, nil)
+			c.writer.status = http.StatusNoContent
+			c.Next()
+			c.writer.WriteHeaderNow()
+			return
+		}
+	}
 
-	if n.handlers == nil {
-		// fmt.Println("not found the route")
+	if method != http.MethodConnect && path != "/" && v.redirect(c, router, method, path) {
 		return
 	}
 
-	c.params = n.params
-
-	for _, h := range n.handlers {
-		h(c)
-		c.GetQueryArray("xs")
-		c.GetQueryMap("ids")
+	if v.HandleMethodNotAllowed {
+		if allow := v.allowed(router, method, path); allow != "" {
+			c.SetHeader("Allow", allow)
+			v.setChain(c, router, v.noMethod)
+			serveError(c, http.StatusMethodNotAllowed, default405Body)
+			return
+		}
 	}
+
+	v.setChain(c, router, v.noRoute)
+	serveError(c, http.StatusNotFound, default404Body)
 }
 
-func (v *Gov) handle(m, path string) (ret nodeValue) {
-	r := v.Router.trees.get(m)
-
-	if len(path) > len(r.path) {
-		// not this node
-
-		p := splitBy('/', path)
-
-		children := r.children
-	GOV:
-		for {
-			for _, child := range children {
-				if child.path == p[0] {
-					p = p[1:]
-					if len(p) > 0 {
-						children = child.children
-						ret.fullPath += "/" + child.path
-						continue GOV
-					}
-					ret.handlers = child.handlers
-					return
-				}
-
-				if child.nType == param {
-					ret.params = append(ret.params, Param{
-						Key:   child.name,
-						Value: p[0],
-					})
-					p = p[1:]
-					if len(p) > 0 {
-						ret.fullPath += "/" + child.path
-						children = child.children
-						continue GOV
-					}
-
-					ret.handlers = child.handlers
-					return
-				}
-
-			}
+// redirect answers with a redirect to the first alternate form of path that
+// has a route, as enabled by RedirectTrailingSlash and RedirectFixedPath.
+func (v *Gov) redirect(c *Context, router *Router, method, path string) bool {
+	root := router.currentTrees().get(method)
+	if root == nil {
+		return false
+	}
 
-			return
+	found := func(p string) bool {
+		return p != path && root.getValue(p, c.params).handlers != nil
+	}
+
+	if v.RedirectTrailingSlash {
+		if alt := toggleTrailingSlash(path); found(alt) {
+			return redirectTo(c, alt)
 		}
+	}
 
+	if v.RedirectFixedPath {
+		fixed := cleanPath(path)
+		if found(fixed) {
+			return redirectTo(c, fixed)
+		}
+		if alt := toggleTrailingSlash(fixed); v.RedirectTrailingSlash && found(alt) {
+			return redirectTo(c, alt)
+		}
 	}
-	ret.handlers = r.handlers
-	return
+	return false
 }
 
-func (v *Gov) handleRequest(method, path string) *node {
-	root := v.Router.trees.get(method)
+// redirectTo redirects permanently to the decoded path, escaping it and
+// keeping the query string and the prefix of a Mount. GET requests get a
+// 301, others a 308 so that the method and body are kept. It reports
+// whether it redirected: paths a browser could read as protocol-relative,
+// like "/\evil.com", are refused.
+func redirectTo(c *Context, path string) bool {
+	code := http.StatusMovedPermanently
+	if c.Method() != http.MethodGet {
+		code = http.StatusPermanentRedirect
+	}
+
+	// never emit a protocol-relative location such as "//evil.com", which
+	// browsers also read in "/\evil.com"
+	path = "/" + strings.TrimLeft(path, "/")
+	if strings.HasPrefix(path, "/\\") {
+		return false
+	}
 
-	if len(path) == 1 {
-		return root
+	location := (&url.URL{Path: mountPrefix(c.Request) + path}).EscapedPath()
+	if q := c.Request.URL.RawQuery; q != "" {
+		location += "?" + q
 	}
 
-	// segs := splitBy('/', path)
-	segs := strings.Split(path, "/")
+	http.Redirect(c.Response, c.Request, location, code)
+	c.writer.WriteHeaderNow()
+	return true
+}
+
+// setChain makes the global middlewares, followed by those of a host
+// router and handlers, the chain of c.
+func (v *Gov) setChain(c *Context, router *Router, handlers HandlerChain) {
+	var hostMiddlewares HandlerChain
+	if router != &v.Router {
+		hostMiddlewares = router.Middlewares
+	}
 
-	if root.path == segs[0] && len(segs[1:]) == 0 {
-		return root
-	} else {
-		return matchUrl(root.children, segs[1:])
+	if size := len(v.Router.Middlewares) + len(hostMiddlewares) + len(handlers); cap(c.handlers) < size {
+		c.handlers = make(HandlerChain, 0, size)
 	}
+	c.handlers = append(c.handlers[0:0], v.Router.Middlewares...)
+	c.handlers = append(c.handlers, hostMiddlewares...)
+	c.handlers = append(c.handlers, handlers...)
 }
 
-func matchUrl(ns []*node, path []string) *node {
-	for _, n := range ns {
-		if n.path == path[0] {
-			if len(path[1:]) == 0 {
-				return n
-			}
+// allowed returns the methods other than method that have a route for path,
+// formatted for the Allow header. HEAD and OPTIONS are listed when Gov
+// answers them automatically.
+func (v *Gov) allowed(router *Router, method, path string) string {
+	trees := router.currentTrees()
+	methods := make([]string, 0, len(trees)+2)
+	for _, t := range trees {
+		if t.method == method {
+			continue
+		}
+
+		if t.root.getValue(path, nil).handlers != nil {
+			methods = append(methods, t.method)
+		}
+	}
+	if len(methods) == 0 {
+		return ""
+	}
 
-			return matchUrl(n.children, path[1:])
+	if v.HandleHEAD && method != http.MethodHead && hasMethod(methods, http.MethodGet) && !hasMethod(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if v.HandleOPTIONS && !hasMethod(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	return strings.Join(methods, ", ")
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
 		}
 	}
+	return false
+}
 
-	return nil
+func serveError(c *Context, code int, defaultMessage string) {
+	c.writer.status = code
+	c.Next()
+
+	if c.writer.Written() {
+		return
+	}
+	if c.writer.Status() == code {
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		c.Response.Write([]byte(defaultMessage))
+		return
+	}
+	c.writer.WriteHeaderNow()
 }
-func splitBy(sperator rune, str string) []string {
-	return strings.FieldsFunc(str, func(r rune) bool {
-		return r == sperator
-	})
+
+func (r *Router) handle(m, path string, params Params) nodeValue {
+	root := r.currentTrees().get(m)
+	if root == nil {
+		return nodeValue{}
+	}
+
+	return root.getValue(path, params)
 }
@@ -0,0 +1,85 @@
+package gov
+
+import (
+	"net/http"
+	"testing"
+)
+
+// nopWriter is a ResponseWriter that allocates nothing per request.
+type nopWriter struct {
+	header http.Header
+}
+
+func (w *nopWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *nopWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *nopWriter) WriteHeader(int) {}
+
+func newAllocsRouter() *Gov {
+	r := New()
+	r.Use(func(c *Context) { c.Next() })
+	r.Get("/api/v1/users", func(c *Context) {})
+	r.Get("/api/v1/users/:id", func(c *Context) {})
+	return r
+}
+
+// TestServeHTTPAllocs guards the dispatch path: with pooled contexts,
+// routing and running the handlers must not allocate, whatever the query
+// string holds.
+func TestServeHTTPAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable with the race detector")
+	}
+
+	r := newAllocsRouter()
+	w := &nopWriter{header: http.Header{}}
+
+	tests := []struct {
+		path   string
+		allocs float64
+	}{
+		{"/api/v1/users", 0},
+		{"/api/v1/users?xs=1&ids[a]=2", 0},
+		{"/api/v1/users/42?xs=1", 0},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			r.ServeHTTP(w, req)
+		})
+		if allocs > tt.allocs {
+			t.Errorf("ServeHTTP(%s) made %v allocations, want at most %v", tt.path, allocs, tt.allocs)
+		}
+	}
+}
+
+func BenchmarkServeHTTPStatic(b *testing.B) {
+	r := newAllocsRouter()
+	w := &nopWriter{header: http.Header{}}
+	req, _ := http.NewRequest("GET", "/api/v1/users", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTPParam(b *testing.B) {
+	r := newAllocsRouter()
+	w := &nopWriter{header: http.Header{}}
+	req, _ := http.NewRequest("GET", "/api/v1/users/42", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
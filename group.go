@@ -0,0 +1,115 @@
+package gov
+
+import (
+	"path"
+)
+
+// RouterGroup registers routes under a shared path prefix. Handlers passed
+// to Group or Use on a group only run for routes registered through it.
+type RouterGroup struct {
+	prefix   string
+	handlers HandlerChain
+	router   *Router
+}
+
+func (r *Router) Group(prefix string, handlers ...HandlerFunc) *RouterGroup {
+	return &RouterGroup{
+		prefix:   joinPaths("", prefix),
+		handlers: combineHandlers(nil, handlers),
+		router:   r,
+	}
+}
+
+func (g *RouterGroup) Group(prefix string, handlers ...HandlerFunc) *RouterGroup {
+	return &RouterGroup{
+		prefix:   joinPaths(g.prefix, prefix),
+		handlers: combineHandlers(g.handlers, handlers),
+		router:   g.router,
+	}
+}
+
+// Prefix returns the absolute path prefix of the group.
+func (g *RouterGroup) Prefix() string {
+	return g.prefix
+}
+
+func (g *RouterGroup) Use(middlewares ...HandlerFunc) {
+	g.handlers = append(g.handlers, middlewares...)
+}
+
+func (g *RouterGroup) Handle(method, relativePath string, handlers ...HandlerFunc) error {
+	return g.router.register(method, joinPaths(g.prefix, relativePath), g.chain(handlers), callerSite())
+}
+
+func (g *RouterGroup) add(m, relativePath string, hs ...HandlerFunc) *Route {
+	return g.router.add(m, joinPaths(g.prefix, relativePath), g.chain(hs)...)
+}
+
+// chain returns the group middlewares followed by hs, or nil if hs is empty
+// so that a route made of middlewares only is rejected like any other route
+// without handlers.
+func (g *RouterGroup) chain(hs HandlerChain) HandlerChain {
+	if len(hs) == 0 {
+		return nil
+	}
+	return combineHandlers(g.handlers, hs)
+}
+
+func (g *RouterGroup) Get(path string, handlers ...HandlerFunc) *Route {
+	return g.add("GET", path, handlers...)
+}
+
+func (g *RouterGroup) Post(path string, handlers ...HandlerFunc) *Route {
+	return g.add("POST", path, handlers...)
+}
+
+func (g *RouterGroup) Put(path string, handlers ...HandlerFunc) *Route {
+	return g.add("PUT", path, handlers...)
+}
+
+func (g *RouterGroup) Delete(path string, handlers ...HandlerFunc) *Route {
+	return g.add("DELETE", path, handlers...)
+}
+
+func (g *RouterGroup) Options(path string, handlers ...HandlerFunc) *Route {
+	return g.add("OPTIONS", path, handlers...)
+}
+
+func (g *RouterGroup) Patch(path string, handlers ...HandlerFunc) *Route {
+	return g.add("PATCH", path, handlers...)
+}
+
+func (g *RouterGroup) Head(path string, handlers ...HandlerFunc) *Route {
+	return g.add("HEAD", path, handlers...)
+}
+
+func (g *RouterGroup) Any(path string, handlers ...HandlerFunc) *Route {
+	var route *Route
+	for _, m := range anyMethods {
+		route = g.add(m, path, handlers...)
+	}
+	return route
+}
+
+// combineHandlers returns a new chain so that appending to a group's
+// handlers never leaks into routes that were already registered.
+func combineHandlers(base, hs HandlerChain) HandlerChain {
+	merged := make(HandlerChain, 0, len(base)+len(hs))
+	merged = append(merged, base...)
+	return append(merged, hs...)
+}
+
+func joinPaths(prefix, relativePath string) string {
+	if relativePath == "" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+
+	joined := path.Join("/", prefix, relativePath)
+	if relativePath[len(relativePath)-1] == '/' && joined[len(joined)-1] != '/' {
+		return joined + "/"
+	}
+	return joined
+}
@@ -0,0 +1,74 @@
+package gov
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cHandler serves h2c connections while H2C is set and passes the other
+// requests, and all of them over TLS, to next.
+type h2cHandler struct {
+	v    *Gov
+	h2c  http.Handler
+	next http.Handler
+}
+
+func (h *h2cHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.v.H2C || r.TLS != nil || !isH2C(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	// the connection is hijacked from v.Server and served as h2c until it
+	// is closed, so Shutdown has to wait for it separately
+	h.v.h2cConns.Add(1)
+	defer h.v.h2cConns.Done()
+	h.h2c.ServeHTTP(w, r)
+}
+
+// isH2C reports whether the request starts an h2c connection, with the
+// HTTP/2 preface or by asking to upgrade, as the h2c package checks it.
+func isH2C(r *http.Request) bool {
+	if r.Method == "PRI" && len(r.Header) == 0 && r.URL.Path == "*" && r.Proto == "HTTP/2.0" {
+		return true
+	}
+	return httpguts.HeaderValuesContainsToken(r.Header["Upgrade"], "h2c") &&
+		httpguts.HeaderValuesContainsToken(r.Header["Connection"], "HTTP2-Settings")
+}
+
+// wrapH2C wraps the handler of v.Server with an h2cHandler unless it is
+// already. The HTTP/2 server takes its timeouts from v.Server, which sends
+// GOAWAY to the h2c connections on Shutdown.
+func (v *Gov) wrapH2C() error {
+	if h, ok := v.Server.Handler.(*h2cHandler); ok && h.v == v {
+		return nil
+	}
+
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(v.Server, h2s); err != nil {
+		return err
+	}
+	next := v.Server.Handler
+	v.Server.Handler = &h2cHandler{v: v, h2c: h2c.NewHandler(next, h2s), next: next}
+	return nil
+}
+
+// waitH2C waits until the h2c connections are closed or ctx is done.
+func (v *Gov) waitH2C(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		v.h2cConns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
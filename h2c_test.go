@@ -0,0 +1,211 @@
+package gov
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// h2cClient talks HTTP/2 over cleartext with prior knowledge.
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+func protoServer(t *testing.T, h2c bool) (string, *Gov, <-chan error) {
+	r := New()
+	r.H2C = h2c
+	r.Get("/proto", func(c *Context) { c.String(c.Proto()) })
+
+	url, errc := startServer(t, r)
+	waitFor(t, errc, func() (*http.Response, error) { return http.Get(url + "/proto") })
+	return url, r, errc
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	url, r, errc := protoServer(t, true)
+
+	client := h2cClient()
+	resp, err := client.Get(url + "/proto")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, resp.ProtoMajor)
+		assert.Equal(t, "HTTP/2.0\n", readBody(t, resp))
+	}
+	client.CloseIdleConnections()
+
+	// HTTP/1.1 clients are still served
+	resp, err = http.Get(url + "/proto")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "HTTP/1.1\n", readBody(t, resp))
+	}
+
+	assert.NoError(t, r.Shutdown(context.Background()))
+	assert.NoError(t, <-errc)
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	url, r, errc := protoServer(t, true)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// the HTTP/1.1 request is answered over HTTP/2 as stream 1
+	_, err = conn.Write([]byte("GET /proto HTTP/1.1\r\nHost: gov\r\nConnection: Upgrade, HTTP2-Settings\r\n" +
+		"Upgrade: h2c\r\nHTTP2-Settings: \r\n\r\n"))
+	assert.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "h2c", resp.Header.Get("Upgrade"))
+
+	_, err = conn.Write([]byte(http2.ClientPreface))
+	assert.NoError(t, err)
+	framer := http2.NewFramer(conn, br)
+	assert.NoError(t, framer.WriteSettings())
+	decoder := hpack.NewDecoder(4096, nil)
+
+	status, body := readStream(t, framer, decoder, 1)
+	assert.Equal(t, "200", status)
+	assert.Equal(t, "HTTP/1.1\n", body)
+
+	// the next requests on the connection are HTTP/2
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	for _, f := range [][2]string{{":method", "GET"}, {":scheme", "http"}, {":authority", "gov"}, {":path", "/proto"}} {
+		encoder.WriteField(hpack.HeaderField{Name: f[0], Value: f[1]})
+	}
+	assert.NoError(t, framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      3,
+		BlockFragment: block.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	}))
+
+	status, body = readStream(t, framer, decoder, 3)
+	assert.Equal(t, "200", status)
+	assert.Equal(t, "HTTP/2.0\n", body)
+	conn.Close()
+
+	assert.NoError(t, r.Shutdown(context.Background()))
+	assert.NoError(t, <-errc)
+}
+
+// readStream reads the frames of a connection until the response of the
+// stream ends and returns its status and body.
+func readStream(t *testing.T, framer *http2.Framer, decoder *hpack.Decoder, id uint32) (status, body string) {
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Header().StreamID != id {
+			continue
+		}
+
+		switch f := frame.(type) {
+		case *http2.HeadersFrame:
+			fields, err := decoder.DecodeFull(f.HeaderBlockFragment())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range fields {
+				if field.Name == ":status" {
+					status = field.Value
+				}
+			}
+		case *http2.DataFrame:
+			body += string(f.Data())
+		}
+		if frame.Header().Flags.Has(http2.FlagDataEndStream) {
+			return status, body
+		}
+	}
+}
+
+func TestH2CDisabled(t *testing.T) {
+	url, r, errc := protoServer(t, false)
+
+	_, err := h2cClient().Get(url + "/proto")
+	assert.Error(t, err)
+
+	assert.NoError(t, r.Shutdown(context.Background()))
+	assert.NoError(t, <-errc)
+}
+
+func TestH2CLaterRun(t *testing.T) {
+	r := New()
+	r.Get("/proto", func(c *Context) { c.String(c.Proto()) })
+
+	for _, h2c := range []bool{true, false, true} {
+		r.H2C = h2c
+		r.Server = &http.Server{}
+		url, errc := startServer(t, r)
+
+		resp := waitFor(t, errc, func() (*http.Response, error) { return http.Get(url + "/proto") })
+		assert.Equal(t, "HTTP/1.1\n", readBody(t, resp))
+
+		client := h2cClient()
+		resp, err := client.Get(url + "/proto")
+		if h2c && assert.NoError(t, err) {
+			assert.Equal(t, "HTTP/2.0\n", readBody(t, resp))
+		} else if !h2c {
+			assert.Error(t, err)
+		}
+		client.CloseIdleConnections()
+
+		assert.NoError(t, r.Shutdown(context.Background()))
+		assert.NoError(t, <-errc)
+	}
+}
+
+func TestH2CShutdownIgnoresHTTP1(t *testing.T) {
+	r := New()
+	r.H2C = true
+	hijacked, release := make(chan net.Conn, 1), make(chan struct{})
+	defer close(release)
+	r.Get("/ws", func(c *Context) {
+		conn, _, err := c.Response.(http.Hijacker).Hijack()
+		if assert.NoError(t, err) {
+			hijacked <- conn
+		}
+		<-release
+	})
+
+	url, errc := startServer(t, r)
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: gov\r\n\r\n"))
+	assert.NoError(t, err)
+	defer (<-hijacked).Close()
+
+	// like other hijacked connections, a websocket is not waited for
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, r.Shutdown(ctx))
+	assert.NoError(t, <-errc)
+}
@@ -0,0 +1,117 @@
+package gov
+
+import (
+	"strings"
+)
+
+// hostRouter holds the routes of a virtual host. Labels starting with ':'
+// capture one label of the request host, e.g. ":sub.example.com".
+type hostRouter struct {
+	pattern string
+	labels  []string
+	params  int
+	router  *Router
+}
+
+// Host returns the router serving requests for the hosts matching pattern,
+// which is either an exact host like "api.example.com" or has param labels
+// like ":tenant.example.com". Host params are exposed through Context.Param
+// like path params. Exact hosts are tried before patterns with params;
+// requests matching no host are served by the routes registered on Gov.
+//
+// Routes and middlewares registered on the returned Router only apply to
+// that host, while the middlewares passed to Gov.Use run for every host.
+func (v *Gov) Host(pattern string) *Router {
+	pattern = strings.ToLower(pattern)
+	for _, h := range v.hosts {
+		if h.pattern == pattern {
+			return h.router
+		}
+	}
+
+	h := &hostRouter{
+		pattern: pattern,
+		labels:  strings.Split(pattern, "."),
+		router: &Router{
+			trees:       make(methodTrees, 0, 9),
+			Middlewares: make(HandlerChain, 0),
+		},
+	}
+	for _, label := range h.labels {
+		if label == "" || label == ":" {
+			panic("invalid host pattern '" + pattern + "'")
+		}
+		if label[0] == ':' {
+			h.params++
+		}
+	}
+
+	// keep exact hosts ahead of the ones with params
+	i := len(v.hosts)
+	if h.params == 0 {
+		for i > 0 && v.hosts[i-1].params > 0 {
+			i--
+		}
+	}
+	v.hosts = append(v.hosts, nil)
+	copy(v.hosts[i+1:], v.hosts[i:])
+	v.hosts[i] = h
+
+	return h.router
+}
+
+func (h *hostRouter) maxParams() int {
+	return h.params + h.router.currentMaxParams()
+}
+
+// match reports whether host matches h, appending the host params to params.
+func (h *hostRouter) match(host string, params Params) (Params, bool) {
+	last := len(h.labels) - 1
+
+	for i, label := range h.labels {
+		end := strings.IndexByte(host, '.')
+		if (end < 0) != (i == last) {
+			return params, false
+		}
+		if end < 0 {
+			end = len(host)
+		}
+
+		part := host[:end]
+		if label[0] == ':' {
+			if part == "" {
+				return params, false
+			}
+			params = append(params, Param{Key: label[1:], Value: part})
+		} else if !strings.EqualFold(label, part) {
+			return params, false
+		}
+
+		if i != last {
+			host = host[end+1:]
+		}
+	}
+	return params, true
+}
+
+// routerForHost returns the router for the request host and stores the
+// host params in c.
+func (v *Gov) routerForHost(c *Context) *Router {
+	host := stripHostPort(c.Request.Host)
+
+	for _, h := range v.hosts {
+		if params, ok := h.match(host, c.params[0:0]); ok {
+			c.params = params
+			return h.router
+		}
+	}
+	return &v.Router
+}
+
+// stripHostPort removes the port and a trailing dot from host.
+func stripHostPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
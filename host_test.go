@@ -0,0 +1,86 @@
+package gov
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performHostRequest(r http.Handler, method, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHostRouting(t *testing.T) {
+	r := New()
+	trace := ""
+
+	r.Use(func(c *Context) { trace += "global " })
+	r.Get("/users/:id", func(c *Context) { trace += "default:" + c.Param("id").(string) })
+
+	api := r.Host("API.example.com")
+	api.Use(func(c *Context) { trace += "api " })
+	api.Get("/users/:id", func(c *Context) { trace += "api:" + c.Param("id").(string) })
+
+	tenant := r.Host(":tenant.example.com")
+	tenant.Get("/users/:id", func(c *Context) {
+		trace += "tenant:" + c.Param("tenant").(string) + ":" + c.Param("id").(string)
+	})
+
+	admin := r.Host("admin.example.com")
+	admin.Group("/v1").Get("/stats", func(c *Context) { trace += "admin" })
+
+	tests := []struct {
+		host  string
+		path  string
+		code  int
+		trace string
+	}{
+		{"api.example.com", "/users/1", http.StatusOK, "global api api:1"},
+		{"api.example.com:8080", "/users/2", http.StatusOK, "global api api:2"},
+		{"acme.example.com", "/users/3", http.StatusOK, "global tenant:acme:3"},
+		{"admin.example.com", "/v1/stats", http.StatusOK, "global admin"},
+		{"admin.example.com", "/users/4", http.StatusNotFound, "global "},
+		{"example.com", "/users/5", http.StatusOK, "global default:5"},
+		{"a.b.example.com", "/users/6", http.StatusOK, "global default:6"},
+		{"localhost", "/users/7", http.StatusOK, "global default:7"},
+	}
+
+	for _, tt := range tests {
+		trace = ""
+		w := performHostRequest(r, "GET", tt.host, tt.path)
+		assert.Equal(t, tt.code, w.Code, tt.host+tt.path)
+		assert.Equal(t, tt.trace, trace, tt.host+tt.path)
+	}
+
+	assert.Equal(t, api, r.Host("api.example.com"))
+	assert.Equal(t, "admin.example.com", r.hosts[1].pattern)
+}
+
+func TestHostMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.Post("/items", func(c *Context) {})
+	r.Host("api.example.com").Put("/items", func(c *Context) {})
+
+	w := performHostRequest(r, "GET", "api.example.com", "/items")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "PUT, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestHostPatternInvalid(t *testing.T) {
+	r := New()
+	assert.Panics(t, func() { r.Host("api..example.com") })
+	assert.Panics(t, func() { r.Host(":.example.com") })
+}
+
+func TestStripHostPort(t *testing.T) {
+	assert.Equal(t, "example.com", stripHostPort("example.com:80"))
+	assert.Equal(t, "example.com", stripHostPort("example.com."))
+	assert.Equal(t, "[::1]", stripHostPort("[::1]:80"))
+	assert.Equal(t, "[::1]", stripHostPort("[::1]"))
+}
@@ -0,0 +1,174 @@
+package gov
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// RunUnix is like Run on the Unix socket file. A stale socket left by a
+// process that did not shut down is replaced, while a socket still accepting
+// connections or any other kind of file makes it fail. Unless perm is 0 the
+// socket permissions are set to perm, e.g. 0660 to let a reverse proxy of
+// the same group connect. The socket file is removed on shutdown.
+func (v *Gov) RunUnix(file string, perm os.FileMode) error {
+	if err := removeStaleSocket(file); err != nil {
+		return err
+	}
+
+	l, err := listenUnix(file, perm)
+	if err != nil {
+		return err
+	}
+	return v.serve(nil, l)
+}
+
+// listenUnix listens on the socket file with the permissions perm, or those
+// of the umask if perm is 0. The socket is created in a private directory
+// and only linked to file once perm is set, so that it is never reachable
+// with wider permissions.
+func listenUnix(file string, perm os.FileMode) (net.Listener, error) {
+	if perm == 0 {
+		return net.Listen("unix", file)
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(file), ".gov")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+
+	// unlike a rename, the link fails if file exists
+	if err = os.Chmod(tmp, perm); err == nil {
+		err = os.Link(tmp, file)
+	}
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: l, file: file}, nil
+}
+
+// unixListener is a listener whose socket was linked to file, which it
+// reports as its address and removes when closed.
+type unixListener struct {
+	*net.UnixListener
+	file string
+	once sync.Once
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.file, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() { os.Remove(l.file) })
+	return err
+}
+
+func removeStaleSocket(file string) error {
+	info, err := os.Lstat(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("gov: %s exists and is not a socket", file)
+	}
+	if conn, err := net.Dial("unix", file); err == nil {
+		conn.Close()
+		return fmt.Errorf("gov: socket %s is in use", file)
+	}
+	return os.Remove(file)
+}
+
+// listenFdsStart is the first file descriptor passed by socket activation.
+const listenFdsStart = 3
+
+// InheritedListeners returns the listening sockets passed by the parent
+// process following the systemd socket activation protocol: LISTEN_FDS
+// sockets starting at file descriptor 3, meant for the process LISTEN_PID.
+// It returns none when the variables are not set for this process, and
+// unsets them so that child processes do not inherit them.
+func InheritedListeners() ([]net.Listener, error) {
+	return inheritedListeners(listenFdsStart)
+}
+
+func inheritedListeners(start int) ([]net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("gov: invalid LISTEN_FDS %q", fds)
+	}
+	return fdListeners(start, n)
+}
+
+// fdListeners returns the listeners for the n file descriptors from start,
+// which it takes ownership of.
+func fdListeners(start, n int) ([]net.Listener, error) {
+	files := make([]*os.File, n)
+	for i := range files {
+		files[i] = os.NewFile(uintptr(start+i), "LISTEN_FD_"+strconv.Itoa(start+i))
+	}
+	return fileListeners(files)
+}
+
+// fileListeners returns the listeners for the sockets of files, which it
+// closes.
+func fileListeners(files []*os.File) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(files))
+	for i, f := range files {
+		// FileListener duplicates the descriptor
+		l, err := net.FileListener(f)
+		if err != nil {
+			closeListeners(listeners)
+			closeFiles(files[i:])
+			return nil, fmt.Errorf("gov: inherited socket %s: %w", f.Name(), err)
+		}
+		f.Close()
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// RunInherited is like Run on the InheritedListeners, e.g. for a service
+// started by systemd socket activation.
+func (v *Gov) RunInherited() error {
+	listeners, err := InheritedListeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return errors.New("gov: no inherited listeners, LISTEN_FDS is not set for this process")
+	}
+	return v.serve(nil, listeners...)
+}
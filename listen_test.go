@@ -0,0 +1,138 @@
+package gov
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unixClient(file string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", file)
+		},
+	}}
+}
+
+// waitFor retries get until it succeeds, failing if run returns first.
+func waitFor(t *testing.T, errc <-chan error, get func() (*http.Response, error)) *http.Response {
+	var err error
+	for i := 0; i < 100; i++ {
+		var resp *http.Response
+		if resp, err = get(); err == nil {
+			return resp
+		}
+		select {
+		case err := <-errc:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal(err)
+	return nil
+}
+
+func TestRunUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gov-unix")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "gov.sock")
+
+	// a socket left behind by a crashed process is replaced
+	stale, err := net.Listen("unix", file)
+	assert.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	r := New()
+	r.Get("/", func(c *Context) { c.String("unix") })
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.RunUnix(file, 0600)
+	}()
+
+	resp := waitFor(t, errc, func() (*http.Response, error) { return unixClient(file).Get("http://gov/") })
+	assert.Equal(t, "unix\n", readBody(t, resp))
+
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the private directory the socket was created in is gone
+	entries, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// a socket in use is not taken over
+	assert.Error(t, New().RunUnix(file, 0))
+
+	assert.NoError(t, r.Shutdown(context.Background()))
+	assert.NoError(t, <-errc)
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+
+	regular := filepath.Join(dir, "regular")
+	assert.NoError(t, ioutil.WriteFile(regular, nil, 0600))
+	assert.Error(t, New().RunUnix(regular, 0))
+}
+
+func TestRunMultipleAddresses(t *testing.T) {
+	r := New()
+	r.Get("/", func(c *Context) { c.String("hello") })
+
+	// reserve two free ports for Run
+	addrs := make([]string, 2)
+	for i := range addrs {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		addrs[i] = l.Addr().String()
+		l.Close()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.Run(addrs...)
+	}()
+
+	for _, addr := range addrs {
+		resp := waitFor(t, errc, func() (*http.Response, error) { return http.Get("http://" + addr + "/") })
+		assert.Equal(t, "hello\n", readBody(t, resp))
+	}
+
+	assert.NoError(t, r.Shutdown(context.Background()))
+	assert.NoError(t, <-errc)
+	for _, addr := range addrs {
+		_, err := http.Get("http://" + addr + "/")
+		assert.Error(t, err)
+	}
+
+	// a failing address closes the others
+	assert.Error(t, New().Run("127.0.0.1:0", "127.0.0.1:-1"))
+}
+
+func TestRunListener(t *testing.T) {
+	r := New()
+	r.Get("/", func(c *Context) { c.String("listener") })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.RunListener(l)
+	}()
+
+	resp := waitFor(t, errc, func() (*http.Response, error) { return http.Get("http://" + l.Addr().String() + "/") })
+	assert.Equal(t, "listener\n", readBody(t, resp))
+	assert.NoError(t, r.Shutdown(context.Background()))
+	assert.NoError(t, <-errc)
+}
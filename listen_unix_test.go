@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package gov
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInheritedListeners(t *testing.T) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+
+	listeners, err := InheritedListeners()
+	assert.NoError(t, err)
+	assert.Empty(t, listeners)
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	os.Setenv("LISTEN_FDS", "1")
+	listeners, err = InheritedListeners()
+	assert.NoError(t, err)
+	assert.Empty(t, listeners)
+
+	// pass a listener like systemd would, at the descriptor of a duplicate
+	// owned by nothing else since it gets closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	assert.NoError(t, err)
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	assert.NoError(t, err)
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "1")
+	listeners, err = inheritedListeners(fd)
+	assert.NoError(t, err)
+	if assert.Len(t, listeners, 1) {
+		assert.Equal(t, l.Addr().String(), listeners[0].Addr().String())
+		listeners[0].Close()
+	}
+	assert.Empty(t, os.Getenv("LISTEN_FDS"))
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "two")
+	_, err = InheritedListeners()
+	assert.Error(t, err)
+	assert.Error(t, New().RunInherited())
+}
@@ -0,0 +1,41 @@
+package gov
+
+import "os"
+
+// EnvGovMode is the environment variable the initial mode is read from.
+const EnvGovMode = "GOV_MODE"
+
+const (
+	DebugMode   = "debug"
+	ReleaseMode = "release"
+	TestMode    = "test"
+)
+
+var govMode = DebugMode
+
+func init() {
+	SetMode(os.Getenv(EnvGovMode))
+}
+
+// SetMode sets the mode of the framework. In debug mode, which is the
+// default, Run prints the route table on startup.
+func SetMode(value string) {
+	switch value {
+	case "":
+		govMode = DebugMode
+	case DebugMode, ReleaseMode, TestMode:
+		govMode = value
+	default:
+		panic("gov mode unknown: " + value + " (available modes: debug release test)")
+	}
+}
+
+// Mode returns the current mode.
+func Mode() string {
+	return govMode
+}
+
+// IsDebugging reports whether the framework runs in debug mode.
+func IsDebugging() bool {
+	return govMode == DebugMode
+}
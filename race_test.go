@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package gov
+
+// raceEnabled reports whether the tests run with the race detector, which
+// makes sync.Pool drop items and so breaks allocation counts.
+const raceEnabled = true
@@ -0,0 +1,16 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package gov
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+// RunWithGracefulRestart restarts the process without dropping connections
+// on Unix systems only; elsewhere it returns an error.
+func (v *Gov) RunWithGracefulRestart(timeout time.Duration, addr ...string) error {
+	return errors.New("gov: graceful restart is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,295 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package gov
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// envRestartFds tells a restarted process how many listeners it inherits.
+// They start at file descriptor 3 and are followed by the readiness pipe.
+const envRestartFds = "GOV_RESTART_FDS"
+
+// RunWithGracefulRestart is like RunWithGracefulShutdown, and on SIGHUP or
+// SIGUSR2 it restarts the process without dropping connections: it starts
+// the executable again with the same arguments, passing it the listening
+// sockets, and keeps serving until the new process serves them too, up to
+// timeout. It then stops accepting connections, leaving them to the new
+// process, and shuts down gracefully. If the new process fails to start,
+// the current one carries on.
+//
+// The listeners are inherited from a restarting parent, from systemd socket
+// activation (see InheritedListeners) or created for addr otherwise.
+func (v *Gov) RunWithGracefulRestart(timeout time.Duration, addr ...string) error {
+	listeners, ready, err := restartListeners()
+	if err != nil {
+		return err
+	}
+	if listeners == nil {
+		if listeners, err = InheritedListeners(); err != nil {
+			return err
+		}
+	}
+	if listeners == nil {
+		if listeners, err = listenTCP(addr); err != nil {
+			return err
+		}
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR2)
+	defer signal.Stop(quit)
+
+	pending := trackNewConns(v.Server)
+
+	handoffs := make([]net.Listener, len(listeners))
+	for i, l := range listeners {
+		handoffs[i] = &handoffListener{Listener: l}
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- v.serve(nil, handoffs...)
+	}()
+
+	if ready != nil {
+		// the listeners accept connections already, the parent can stop
+		ready.Write([]byte{1})
+		ready.Close()
+	}
+
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case sig := <-quit:
+			if sig != syscall.SIGHUP && sig != syscall.SIGUSR2 {
+				fmt.Println("[gov] received " + sig.String() + ", shutting down")
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				if err := v.Shutdown(ctx); err != nil {
+					return err
+				}
+				return <-errc
+			}
+
+			fmt.Println("[gov] received " + sig.String() + ", restarting")
+			if err := restart(listeners, timeout); err != nil {
+				fmt.Println("[gov] restart failed: " + err.Error())
+				continue
+			}
+		}
+
+		// the new process accepts the connections from now on
+		for _, l := range handoffs {
+			l.(*handoffListener).stop()
+		}
+		if err := <-errc; err != nil {
+			return err
+		}
+
+		// Shutdown drops the connections whose request it has not read yet
+		pending.wait(time.Now().Add(timeout))
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return v.Shutdown(ctx)
+	}
+}
+
+// restart starts a new process serving the listeners and waits until it
+// does. The listeners keep accepting connections meanwhile.
+func restart(listeners []net.Listener, timeout time.Duration) error {
+	files, err := listenerFiles(listeners)
+	if err != nil {
+		return err
+	}
+	defer closeFiles(files)
+
+	if err := startProcess(files, timeout); err != nil {
+		return err
+	}
+
+	// the socket files now belong to the new process
+	for _, l := range listeners {
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	return nil
+}
+
+// restartListeners returns the listeners passed by a restarting parent and
+// the pipe to tell it when they are served.
+func restartListeners() ([]net.Listener, *os.File, error) {
+	fds := os.Getenv(envRestartFds)
+	if fds == "" {
+		return nil, nil, nil
+	}
+	os.Unsetenv(envRestartFds)
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, nil, fmt.Errorf("gov: invalid %s %q", envRestartFds, fds)
+	}
+
+	listeners, err := fdListeners(listenFdsStart, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listeners, os.NewFile(uintptr(listenFdsStart+n), "gov-ready"), nil
+}
+
+// listenerFiles returns duplicates of the sockets of the listeners, which
+// stay open once the listeners are closed. Unlike the File method of the
+// listeners, it leaves the sockets, shared with the listeners of this
+// process, in non-blocking mode.
+func listenerFiles(listeners []net.Listener) ([]*os.File, error) {
+	files := make([]*os.File, 0, len(listeners))
+	for _, l := range listeners {
+		f, err := dupListener(l)
+		if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func dupListener(l net.Listener) (*os.File, error) {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("gov: cannot pass a %T to another process", l)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	fd, dupErr := -1, error(nil)
+	err = raw.Control(func(s uintptr) {
+		syscall.ForkLock.RLock()
+		defer syscall.ForkLock.RUnlock()
+		if fd, dupErr = syscall.Dup(int(s)); dupErr == nil {
+			syscall.CloseOnExec(fd)
+		}
+	})
+	if err == nil {
+		err = dupErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// os.NewFile keeps the socket non-blocking, so that Fd does not change
+	// it when the file is passed to the new process
+	return os.NewFile(uintptr(fd), l.Addr().String()), nil
+}
+
+// startProcess starts the executable again, passing it the listening
+// sockets, and waits until it serves them.
+func startProcess(files []*os.File, timeout time.Duration) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+	defer readyWriter.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), envRestartFds+"="+strconv.Itoa(len(files)))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files[:len(files):len(files)], readyWriter)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// only the new process holds the writer now, so a crash ends the read
+	readyWriter.Close()
+
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	if _, err := ready.Read(make([]byte, 1)); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("gov: new process %d did not become ready: %w", cmd.Process.Pid, err)
+	}
+
+	fmt.Println("[gov] new process " + strconv.Itoa(cmd.Process.Pid) + " is ready")
+	return cmd.Process.Release()
+}
+
+// handoffListener stops accepting connections without making Serve fail,
+// leaving them to the process the listener was handed to.
+type handoffListener struct {
+	net.Listener
+	stopped int32
+}
+
+func (l *handoffListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil && atomic.LoadInt32(&l.stopped) == 1 {
+		return nil, http.ErrServerClosed
+	}
+	return c, err
+}
+
+func (l *handoffListener) stop() {
+	atomic.StoreInt32(&l.stopped, 1)
+	l.Listener.Close()
+}
+
+// newConns tracks the connections of a server that have not sent a request
+// yet.
+type newConns struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func trackNewConns(srv *http.Server) *newConns {
+	n := &newConns{conns: make(map[net.Conn]struct{})}
+
+	connState := srv.ConnState
+	srv.ConnState = func(c net.Conn, state http.ConnState) {
+		n.mu.Lock()
+		if state == http.StateNew {
+			n.conns[c] = struct{}{}
+		} else {
+			delete(n.conns, c)
+		}
+		n.mu.Unlock()
+
+		if connState != nil {
+			connState(c, state)
+		}
+	}
+	return n
+}
+
+// wait waits until all the tracked connections sent a request or deadline.
+func (n *newConns) wait(deadline time.Time) {
+	for time.Now().Before(deadline) {
+		n.mu.Lock()
+		pending := len(n.conns)
+		n.mu.Unlock()
+
+		if pending == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
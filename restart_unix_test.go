@@ -0,0 +1,183 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package gov
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	envRestartTestAddr  = "GOV_TEST_RESTART_ADDR"
+	envRestartTestFail  = "GOV_TEST_RESTART_FAIL"
+	envRestartTestDelay = "GOV_TEST_RESTART_DELAY"
+)
+
+// TestGracefulRestartHelper is the server restarted by the tests below,
+// running in a process of its own.
+func TestGracefulRestartHelper(t *testing.T) {
+	addr := os.Getenv(envRestartTestAddr)
+	if addr == "" {
+		t.Skip("helper process of TestGracefulRestart")
+	}
+	if os.Getenv(envRestartFds) != "" {
+		if os.Getenv(envRestartTestFail) != "" {
+			os.Exit(3)
+		}
+		// a new process slow to start, e.g. warming caches
+		delay, _ := time.ParseDuration(os.Getenv(envRestartTestDelay))
+		time.Sleep(delay)
+	}
+
+	r := New()
+	r.Get("/pid", func(c *Context) { c.String(strconv.Itoa(os.Getpid())) })
+	r.Get("/slow", func(c *Context) {
+		time.Sleep(300 * time.Millisecond)
+		c.String(strconv.Itoa(os.Getpid()))
+	})
+	if err := r.RunWithGracefulRestart(5*time.Second, addr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// restartHelper runs TestGracefulRestartHelper in a new process and returns
+// it once it serves, with a function getting a path from it.
+func restartHelper(t *testing.T, env ...string) (*exec.Cmd, func(string) (string, error)) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	// the output of the processes, shown if the test fails
+	out, err := ioutil.TempFile("", "gov-restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			log, _ := ioutil.ReadFile(out.Name())
+			t.Logf("processes output:\n%s", log)
+		}
+		out.Close()
+		os.Remove(out.Name())
+	})
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestGracefulRestartHelper$", "-test.v")
+	cmd.Env = append(append(os.Environ(), envRestartTestAddr+"="+addr), env...)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(path string) (string, error) {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return strings.TrimSpace(string(body)), err
+	}
+
+	for i := 0; i < 200; i++ {
+		if _, err = get("/pid"); err == nil {
+			return cmd, get
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(err)
+	return nil, nil
+}
+
+func TestGracefulRestart(t *testing.T) {
+	if os.Getenv(envRestartTestAddr) != "" {
+		t.Skip("running as helper process")
+	}
+
+	cmd, get := restartHelper(t, envRestartTestDelay+"=500ms")
+	parent := strconv.Itoa(cmd.Process.Pid)
+
+	// a request in flight during the restart completes on the old process
+	slow := make(chan string, 1)
+	go func() {
+		body, err := get("/slow")
+		assert.NoError(t, err)
+		slow <- body
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, cmd.Process.Signal(syscall.SIGHUP))
+
+	// requests never fail nor wait while the new process starts and takes
+	// over, the old one serves them until then
+	child, served := parent, 0
+	for i := 0; i < 500 && child == parent; i++ {
+		start := time.Now()
+		body, err := get("/pid")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, time.Since(start) < 200*time.Millisecond, "request took %s", time.Since(start))
+
+		if child = body; child == parent {
+			served++
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.NotEqual(t, parent, child)
+	assert.True(t, served > 10, "the old process served %d requests while the new one started", served)
+	assert.Equal(t, parent, <-slow)
+
+	// the old process exits once done
+	assert.NoError(t, cmd.Wait())
+
+	pid, err := strconv.Atoi(child)
+	if !assert.NoError(t, err) || !assert.True(t, pid > 0) {
+		return
+	}
+	assert.NoError(t, syscall.Kill(pid, syscall.SIGTERM))
+	for i := 0; i < 200 && err == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, err = get("/pid")
+	}
+	assert.Error(t, err)
+}
+
+func TestGracefulRestartFailure(t *testing.T) {
+	if os.Getenv(envRestartTestAddr) != "" {
+		t.Skip("running as helper process")
+	}
+
+	cmd, get := restartHelper(t, envRestartTestFail+"=1")
+	parent := strconv.Itoa(cmd.Process.Pid)
+
+	assert.NoError(t, cmd.Process.Signal(syscall.SIGHUP))
+
+	// the old process keeps serving, including while the new one fails
+	for i := 0; i < 50; i++ {
+		body, err := get("/pid")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, parent, body)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.NoError(t, cmd.Process.Signal(syscall.SIGTERM))
+	assert.NoError(t, cmd.Wait())
+}
@@ -1,7 +1,12 @@
 package gov
 
 import (
-	"strings"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
 )
 
 type HandlerFunc func(*Context)
@@ -10,141 +15,188 @@ type HandlerChain []HandlerFunc
 
 type IRouter interface {
 	Use(...HandlerFunc)
-
-	Get(string, ...HandlerFunc)
-	Post(string, ...HandlerFunc)
-	Put(string, ...HandlerFunc)
-	Delete(string, ...HandlerFunc)
-	Options(string, ...HandlerFunc)
-	Patch(string, ...HandlerFunc)
-	Any(string, ...HandlerFunc)
-	Head(string, ...HandlerFunc)
-}
+	Group(string, ...HandlerFunc) *RouterGroup
+
+	Get(string, ...HandlerFunc) *Route
+	Post(string, ...HandlerFunc) *Route
+	Put(string, ...HandlerFunc) *Route
+	Delete(string, ...HandlerFunc) *Route
+	Options(string, ...HandlerFunc) *Route
+	Patch(string, ...HandlerFunc) *Route
+	Any(string, ...HandlerFunc) *Route
+	Head(string, ...HandlerFunc) *Route
+	Handle(string, string, ...HandlerFunc) error
+	Mount(string, http.Handler)
+	Static(string, string)
+	StaticFile(string, string)
+	StaticFS(string, http.FileSystem)
+}
+
+// anyMethods lists the methods Any registers a route for.
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete,
+	http.MethodConnect, http.MethodTrace,
+}
+
+var (
+	_ IRouter = &Router{}
+	_ IRouter = &RouterGroup{}
+)
 
 type methodTree struct {
 	method string
 	root   *node
 }
 
-type nodeType uint8
-
-const (
-	static nodeType = iota
-	root
-	param
-)
-
-type node struct {
-	path     string
-	handlers HandlerChain
-	children []*node
-	fullpath string
-	nType    nodeType
-	name     string
-}
-
-type nodeValue struct {
-	handlers HandlerChain
-	params   Params
-	fullPath string
-}
-
 type methodTrees []methodTree
 
 type Router struct {
 	trees       methodTrees
 	Middlewares HandlerChain
+	maxParams   int
+	names       map[string]string
+
+	// set by EnableConcurrentRoutes
+	concurrent bool
+	mu         sync.Mutex
+	live       atomic.Value
 }
 
+// RouteInfo describes a registered endpoint. Handler is the name of the
+// last handler of the chain; Middlewares counts the handlers running before
+// it, including the global ones.
 type RouteInfo struct {
-	Method  string
-	Path    string
-	Handler string
+	Method      string
+	Path        string
+	Handler     string
+	Middlewares int
 }
 
 func (route *Router) Use(middlewares ...HandlerFunc) {
 	route.Middlewares = append(route.Middlewares, middlewares...)
 }
 
-func insert2(root *node, m string, p []string, hs ...HandlerFunc) {
-	for _, n := range root.children {
-
-		if n.path == p[0] {
-
-			if len(p[1:]) == 0 {
-				n.handlers = hs
-			} else {
-				insert2(n, m, p[1:], hs...)
-			}
+// RouteError reports a route that could not be registered, along with the
+// route it conflicts with if there is one.
+type RouteError struct {
+	Method       string
+	Path         string
+	Site         string
+	Reason       string
+	Conflict     string
+	ConflictSite string
+}
 
-			return
-		}
+func (e *RouteError) Error() string {
+	msg := e.Method + " " + e.Path
+	if e.Site != "" {
+		msg += " (" + e.Site + ")"
 	}
+	msg += ": " + e.Reason
 
-	var n *node
-	if strings.HasPrefix(p[0], ":") {
-		n = &node{
-			path:     p[0],
-			handlers: hs,
-			children: []*node{},
-			nType:    param,
-			name:     p[0][1:],
-		}
-	} else {
-		n = &node{
-			path:     p[0],
-			handlers: hs,
-			children: []*node{},
+	if e.Conflict != "" {
+		msg += ", conflicts with '" + e.Conflict + "'"
+		if e.ConflictSite != "" {
+			msg += " (" + e.ConflictSite + ")"
 		}
 	}
+	return msg
+}
 
-	root.children = append(root.children, n)
+// Handle registers the handlers for the given method and path. Unlike Get,
+// Post and friends, which panic, it returns a *RouteError when the route is
+// invalid or conflicts with an existing one.
+func (r *Router) Handle(method, path string, handlers ...HandlerFunc) error {
+	return r.register(method, path, handlers, callerSite())
+}
 
-	if len(p[1:]) > 0 {
-		insert2(n, m, p[1:], hs...)
+func (r *Router) add(m, path string, hs ...HandlerFunc) *Route {
+	if err := r.register(m, path, hs, callerSite()); err != nil {
+		panic(err)
 	}
+	return &Route{Path: path, router: r}
 }
 
-func (r *Router) add(m, path string, hs ...HandlerFunc) {
+func (r *Router) register(m, path string, hs HandlerChain, site string) error {
+	if len(hs) == 0 {
+		return &RouteError{Method: m, Path: path, Site: site, Reason: "there must be at least one handler"}
+	}
 
-	root := r.trees.get(m)
+	trees := r.trees
+	if r.concurrent {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		trees = r.trees.clone(m)
+	}
+	root := trees.get(m)
 
 	if root == nil {
-		root = &node{
-			path:     "/",
-			handlers: HandlerChain{},
-			children: []*node{},
-			fullpath: "/",
+		root = new(node)
+		trees = append(trees, methodTree{method: m, root: root})
+		if !r.concurrent {
+			r.trees = trees
 		}
-
-		r.trees = append(r.trees, methodTree{method: m, root: root})
 	}
 
-	if root.path == path {
-		root.handlers = hs
-	} else {
-		segs := strings.Split(path, "/")
+	if err := root.addRoute(path, hs, site); err != nil {
+		err.Method = m
+		err.Path = path
+		err.Site = site
+		return err
+	}
 
-		insert2(root, m, segs[1:], hs...)
+	r.trees = trees
+	if n := countParams(path); n > r.maxParams {
+		r.maxParams = n
 	}
+	if r.concurrent {
+		r.publish()
+	}
+	return nil
+}
+
+func (r *Router) Get(path string, handlers ...HandlerFunc) *Route {
+	return r.add("GET", path, handlers...)
+}
+
+func (r *Router) Post(path string, handlers ...HandlerFunc) *Route {
+	return r.add("POST", path, handlers...)
 }
 
-func (r *Router) Get(path string, handlers ...HandlerFunc) {
-	r.add("GET", path, handlers...)
+func (r *Router) Put(path string, handlers ...HandlerFunc) *Route {
+	return r.add("PUT", path, handlers...)
 }
 
-func (r *Router) Post(path string, handlers ...HandlerFunc) {
-	r.add("POST", path, handlers...)
+func (r *Router) Delete(path string, handlers ...HandlerFunc) *Route {
+	return r.add("DELETE", path, handlers...)
 }
 
-func (r *Router) Put(path string, handlers ...HandlerFunc) {
-	r.add("PUT", path, handlers...)
+func (r *Router) Options(path string, handlers ...HandlerFunc) *Route {
+	return r.add("OPTIONS", path, handlers...)
 }
 
-func (r *Router) Delete(path string, handlers ...HandlerFunc) {
-	r.add("DELETE", path, handlers...)
+func (r *Router) Patch(path string, handlers ...HandlerFunc) *Route {
+	return r.add("PATCH", path, handlers...)
+}
+
+func (r *Router) Head(path string, handlers ...HandlerFunc) *Route {
+	return r.add("HEAD", path, handlers...)
+}
+
+// Any registers the handlers for every method in anyMethods.
+func (r *Router) Any(path string, handlers ...HandlerFunc) *Route {
+	var route *Route
+	for _, m := range anyMethods {
+		route = r.add(m, path, handlers...)
+	}
+	return route
 }
 
+// get returns the tree of method. It stays a linear scan: Handle accepts
+// any method, so the trees cannot be indexed by a fixed set, and scanning
+// the few registered ones costs a couple of nanoseconds per request, less
+// than hashing the method for a map.
 func (trees methodTrees) get(method string) *node {
 	for _, tree := range trees {
 		if tree.method == method {
@@ -156,28 +208,42 @@ func (trees methodTrees) get(method string) *node {
 
 func (r *Router) Routes() []RouteInfo {
 	ret := []RouteInfo{}
-	for _, t := range r.trees {
-		ret = append(ret, iterate("", t.method, t.root)...)
+	for _, t := range r.currentTrees() {
+		ret = r.iterate(ret, t.method, t.root)
 	}
 
 	return ret
-
 }
 
-func iterate(path, method string, root *node) []RouteInfo {
-
-	ret := []RouteInfo{}
-	path += root.path
-
-	ret = append(ret, RouteInfo{
-		Method:  method,
-		Path:    path,
-		Handler: string(len(root.handlers)),
-	})
+func (r *Router) iterate(ret []RouteInfo, method string, n *node) []RouteInfo {
+	if n.handlers != nil {
+		info := RouteInfo{Method: method, Path: n.fullPath, Middlewares: len(r.Middlewares)}
+		// register rejects empty chains, but a route must never break listing
+		if last := len(n.handlers) - 1; last >= 0 {
+			info.Handler = nameOfFunction(n.handlers[last])
+			info.Middlewares += last
+		}
+		ret = append(ret, info)
+	}
 
-	for _, child := range root.children {
-		ret = append(ret, iterate(path, method, child)...)
+	for _, child := range n.children {
+		ret = r.iterate(ret, method, child)
+	}
+	for _, child := range n.paramChildren {
+		ret = r.iterate(ret, method, child)
+	}
+	if n.catchAll != nil {
+		ret = r.iterate(ret, method, n.catchAll)
 	}
 
 	return ret
 }
+
+// PrintRoutes writes the route table to w, one endpoint per line.
+func (r *Router) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', 0)
+	for _, route := range r.Routes() {
+		fmt.Fprintf(tw, "[gov-debug] %s\t%s\t--> %s (%d middlewares)\n", route.Method, route.Path, route.Handler, route.Middlewares)
+	}
+	tw.Flush()
+}
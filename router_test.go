@@ -0,0 +1,410 @@
+package gov
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouterGroup(t *testing.T) {
+	r := New()
+	trace := ""
+
+	v1 := r.Group("/v1", func(c *Context) { trace += "v1 " })
+	v1.Get("/users", func(c *Context) { trace += "users" })
+
+	admin := v1.Group("admin")
+	admin.Use(func(c *Context) { trace += "admin " })
+	admin.Get("/stats", func(c *Context) { trace += "stats" })
+
+	r.Get("/health", func(c *Context) { trace += "health" })
+
+	performRequest(r, "GET", "/v1/users")
+	assert.Equal(t, "v1 users", trace)
+
+	trace = ""
+	performRequest(r, "GET", "/v1/admin/stats")
+	assert.Equal(t, "v1 admin stats", trace)
+
+	trace = ""
+	performRequest(r, "GET", "/health")
+	assert.Equal(t, "health", trace)
+
+	assert.Equal(t, "/v1/admin", admin.Prefix())
+}
+
+func TestRouterGroupUseAfterRegistration(t *testing.T) {
+	r := New()
+	trace := ""
+
+	g := r.Group("/g")
+	g.Get("/a", func(c *Context) { trace += "a" })
+	g.Use(func(c *Context) { trace += "mw " })
+	g.Get("/b", func(c *Context) { trace += "b" })
+
+	performRequest(r, "GET", "/g/a")
+	assert.Equal(t, "a", trace)
+
+	trace = ""
+	performRequest(r, "GET", "/g/b")
+	assert.Equal(t, "mw b", trace)
+}
+
+func TestJoinPaths(t *testing.T) {
+	assert.Equal(t, "/", joinPaths("", ""))
+	assert.Equal(t, "/v1", joinPaths("/v1", ""))
+	assert.Equal(t, "/v1/users", joinPaths("/v1", "/users"))
+	assert.Equal(t, "/v1/users/", joinPaths("/v1", "users/"))
+	assert.Equal(t, "/v1/users", joinPaths("/v1/", "/users"))
+}
+
+func TestRouterMethods(t *testing.T) {
+	r := New()
+	hit := ""
+	handler := func(c *Context) { hit = c.Method() }
+
+	r.Options("/options", handler)
+	r.Patch("/patch", handler)
+	r.Head("/head", handler)
+	r.Any("/any", handler)
+
+	performRequest(r, "OPTIONS", "/options")
+	assert.Equal(t, "OPTIONS", hit)
+
+	performRequest(r, "PATCH", "/patch")
+	assert.Equal(t, "PATCH", hit)
+
+	performRequest(r, "HEAD", "/head")
+	assert.Equal(t, "HEAD", hit)
+
+	for _, m := range anyMethods {
+		hit = ""
+		performRequest(r, m, "/any")
+		assert.Equal(t, m, hit)
+	}
+}
+
+func TestRouterCatchAll(t *testing.T) {
+	r := New()
+	var value interface{}
+
+	r.Get("/static/*filepath", func(c *Context) { value = c.Param("filepath") })
+	r.Get("/static/index", func(c *Context) { value = "index" })
+
+	performRequest(r, "GET", "/static/css/app.css")
+	assert.Equal(t, "/css/app.css", value)
+
+	performRequest(r, "GET", "/static/js/")
+	assert.Equal(t, "/js/", value)
+
+	performRequest(r, "GET", "/static/index")
+	assert.Equal(t, "index", value)
+}
+
+func TestRouterCatchAllConflicts(t *testing.T) {
+	r := New()
+	r.Get("/src/*filepath", func(c *Context) {})
+
+	assert.Panics(t, func() { r.Get("/src/*rest", func(c *Context) {}) })
+	assert.Panics(t, func() { r.Get("/files/*filepath/edit", func(c *Context) {}) })
+	assert.Panics(t, func() { r.Get("/files/*", func(c *Context) {}) })
+}
+
+func TestRouterConflicts(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(c *Context) {})
+
+	assert.Panics(t, func() { r.Get("/users/:id", func(c *Context) {}) })
+	assert.Panics(t, func() { r.Get("/users/:name/posts", func(c *Context) {}) })
+	assert.NotPanics(t, func() { r.Post("/users/:id", func(c *Context) {}) })
+
+	err := r.Handle("GET", "/users/:id", func(c *Context) {})
+	if assert.Error(t, err) {
+		routeErr := err.(*RouteError)
+		assert.Equal(t, "GET", routeErr.Method)
+		assert.Equal(t, "/users/:id", routeErr.Conflict)
+		assert.Contains(t, routeErr.Site, "router_test.go")
+		assert.Contains(t, routeErr.ConflictSite, "router_test.go")
+		assert.Contains(t, err.Error(), "already registered")
+	}
+
+	g := r.Group("/api")
+	assert.NoError(t, g.Handle("GET", "/users/:id", func(c *Context) {}))
+	assert.Error(t, g.Handle("GET", "/users/:uid", func(c *Context) {}))
+}
+
+func TestRouterNoHandlers(t *testing.T) {
+	r := New()
+
+	err := r.Handle("GET", "/empty")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "there must be at least one handler")
+	}
+	assert.Panics(t, func() { r.Get("/empty") })
+	assert.Panics(t, func() { r.Group("/g").Get("/empty") })
+	assert.Panics(t, func() { r.Group("/g", func(c *Context) {}).Get("/empty") })
+
+	// the rejected routes were not registered
+	r.Get("/empty", func(c *Context) {})
+	assert.Equal(t, http.StatusNotFound, performRequest(r, "GET", "/g/empty").Code)
+}
+
+func TestRouterNotFound(t *testing.T) {
+	r := New()
+	r.Get("/users", func(c *Context) {})
+
+	w := performRequest(r, "GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404 page not found", w.Body.String())
+
+	r.NoRoute(func(c *Context) { c.Json(map[string]string{"error": "not found"}) })
+	w = performRequest(r, "GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "{\"error\":\"not found\"}\n", w.Body.String())
+
+	r.NoRoute(func(c *Context) { c.Status(http.StatusTeapot) })
+	w = performRequest(r, "GET", "/missing")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(c *Context) {})
+	r.Put("/users/:id", func(c *Context) {})
+	r.Post("/users", func(c *Context) {})
+
+	w := performRequest(r, "DELETE", "/users/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, PUT, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.Equal(t, "405 method not allowed", w.Body.String())
+
+	r.NoMethod(func(c *Context) { c.String("nope") })
+	w = performRequest(r, "GET", "/users")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "POST, OPTIONS", w.Header().Get("Allow"))
+	assert.Equal(t, "nope\n", w.Body.String())
+
+	r.HandleMethodNotAllowed = false
+	w = performRequest(r, "GET", "/users")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Allow"))
+}
+
+func TestRouterHeadFallback(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(c *Context) {
+		id := c.Param("id").(string)
+		c.SetHeader("X-User", id)
+		c.String("user " + id)
+	})
+	r.Get("/sized", func(c *Context) {
+		c.SetHeader("Content-Length", "42")
+		c.Status(http.StatusAccepted)
+	})
+	r.Head("/explicit", func(c *Context) { c.SetHeader("X-Head", "yes") })
+	r.Get("/explicit", func(c *Context) { c.SetHeader("X-Head", "no") })
+
+	w := performRequest(r, "HEAD", "/users/7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "7", w.Header().Get("X-User"))
+	assert.Equal(t, strconv.Itoa(len("user 7\n")), w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(r, "HEAD", "/sized")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "42", w.Header().Get("Content-Length"))
+
+	w = performRequest(r, "HEAD", "/explicit")
+	assert.Equal(t, "yes", w.Header().Get("X-Head"))
+
+	r.HandleHEAD = false
+	w = performRequest(r, "HEAD", "/users/7")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestRouterOptions(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(c *Context) {})
+	r.Delete("/users/:id", func(c *Context) {})
+	r.Post("/users", func(c *Context) {})
+	r.Options("/custom", func(c *Context) { c.Status(http.StatusTeapot) })
+
+	w := performRequest(r, "OPTIONS", "/users/1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, DELETE, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(r, "OPTIONS", "/custom")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+
+	w = performRequest(r, "OPTIONS", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	r.HandleOPTIONS = false
+	w = performRequest(r, "OPTIONS", "/users/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, DELETE, HEAD", w.Header().Get("Allow"))
+}
+
+func TestRouterStatusWithoutBody(t *testing.T) {
+	r := New()
+	r.Delete("/users/:id", func(c *Context) { c.Status(http.StatusNoContent) })
+
+	w := performRequest(r, "DELETE", "/users/1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRouterRedirectTrailingSlash(t *testing.T) {
+	r := New()
+	r.Get("/users", func(c *Context) {})
+	r.Get("/posts/", func(c *Context) {})
+	r.Post("/users/:id", func(c *Context) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{"GET", "/users/", http.StatusMovedPermanently, "/users"},
+		{"GET", "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{"GET", "/posts", http.StatusMovedPermanently, "/posts/"},
+		{"POST", "/users/1/", http.StatusPermanentRedirect, "/users/1"},
+		{"GET", "/users", http.StatusOK, ""},
+		{"GET", "/", http.StatusNotFound, ""},
+		{"GET", "//users", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		w := performRequest(r, tt.method, tt.path)
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		assert.Equal(t, tt.location, w.Header().Get("Location"), tt.path)
+	}
+
+	r.RedirectTrailingSlash = false
+	w := performRequest(r, "GET", "/users/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the location is escaped and never leaves the host
+	r = New()
+	r.Get("/:page", func(c *Context) {})
+	r.Get("/files/:name", func(c *Context) {})
+
+	w = performRequest(r, "GET", "/files/a%3Fb/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/files/a%3Fb", w.Header().Get("Location"))
+
+	w = performRequest(r, "GET", "/%5Cevil.com/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestRouterRedirectFixedPath(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.Get("/users", func(c *Context) {})
+	r.Put("/users/:id/", func(c *Context) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{"GET", "//users", http.StatusMovedPermanently, "/users"},
+		{"GET", "/posts/../users", http.StatusMovedPermanently, "/users"},
+		{"GET", "/./users/", http.StatusMovedPermanently, "/users"},
+		{"PUT", "/users//1", http.StatusPermanentRedirect, "/users/1/"},
+		{"GET", "//evil.com/", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		req.URL.Path = tt.path
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		assert.Equal(t, tt.location, w.Header().Get("Location"), tt.path)
+	}
+
+	// the location is escaped and never leaves the host
+	r = New()
+	r.RedirectFixedPath = true
+	r.Get("/:page", func(c *Context) {})
+	r.Get("/files/:name", func(c *Context) {})
+
+	w := performRequest(r, "GET", "/docs/../files/a%3Fb")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/files/a%3Fb", w.Header().Get("Location"))
+
+	w = performRequest(r, "GET", "/docs/..//%5Cevil.com")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func routesTestHandler(c *Context) {}
+
+func routesTestMiddleware(c *Context) {}
+
+func TestRouterRoutes(t *testing.T) {
+	r := New()
+	r.Use(routesTestMiddleware)
+	r.Get("/", routesTestHandler)
+	r.Get("/users/:id", routesTestMiddleware, routesTestHandler)
+	r.Get("/users/:id/posts", routesTestHandler)
+	r.Post("/static/*filepath", routesTestHandler)
+
+	handler := "github.com/vritser/gov.routesTestHandler"
+	assert.Equal(t, []RouteInfo{
+		{Method: "GET", Path: "/", Handler: handler, Middlewares: 1},
+		{Method: "GET", Path: "/users/:id", Handler: handler, Middlewares: 2},
+		{Method: "GET", Path: "/users/:id/posts", Handler: handler, Middlewares: 1},
+		{Method: "POST", Path: "/static/*filepath", Handler: handler, Middlewares: 1},
+	}, r.Routes())
+
+	// a route without handlers, which register rejects, does not break it
+	assert.Nil(t, r.trees.get("GET").addRoute("/empty", HandlerChain{}, ""))
+	assert.Contains(t, r.Routes(), RouteInfo{Method: "GET", Path: "/empty", Middlewares: 1})
+	assert.NotPanics(t, func() { r.PrintRoutes(new(bytes.Buffer)) })
+}
+
+func TestRouterPrintRoutes(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", routesTestHandler)
+	r.Delete("/users/:id", routesTestMiddleware, routesTestHandler)
+
+	buf := new(bytes.Buffer)
+	r.PrintRoutes(buf)
+
+	assert.Equal(t, ""+
+		"[gov-debug] GET    /users/:id --> github.com/vritser/gov.routesTestHandler (0 middlewares)\n"+
+		"[gov-debug] DELETE /users/:id --> github.com/vritser/gov.routesTestHandler (1 middlewares)\n",
+		buf.String())
+}
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Mode())
+
+	SetMode("")
+	assert.True(t, IsDebugging())
+
+	SetMode(ReleaseMode)
+	assert.Equal(t, ReleaseMode, Mode())
+	assert.False(t, IsDebugging())
+
+	assert.Panics(t, func() { SetMode("unknown") })
+}
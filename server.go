@@ -0,0 +1,159 @@
+package gov
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Run listens on the TCP addresses, ":9000" by default, and serves requests
+// on all of them until the server fails or Shutdown is called, in which
+// case it returns nil without waiting for the requests in flight (Shutdown
+// does).
+func (v *Gov) Run(addr ...string) error {
+	listeners, err := listenTCP(addr)
+	if err != nil {
+		return err
+	}
+	return v.serve(nil, listeners...)
+}
+
+// listenTCP listens on the addresses, ":9000" if there are none.
+func listenTCP(addr []string) ([]net.Listener, error) {
+	if len(addr) == 0 {
+		addr = []string{":9000"}
+	}
+
+	listeners := make([]net.Listener, 0, len(addr))
+	for _, address := range addr {
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// RunListener is like Run on a listener created by the caller.
+func (v *Gov) RunListener(l net.Listener) error {
+	return v.serve(nil, l)
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// serve serves requests on the listeners with v.Server, over TLS if config
+// is not nil or with h2c if H2C is set, until all of them are closed. When
+// one fails, the server is closed.
+func (v *Gov) serve(config *tls.Config, listeners ...net.Listener) error {
+	if v.Server.Handler == nil {
+		v.Server.Handler = v
+	}
+	if config != nil {
+		// ServeTLS enables HTTP/2 on a copy of TLSConfig
+		v.Server.TLSConfig = config
+	} else if v.H2C {
+		if err := v.wrapH2C(); err != nil {
+			closeListeners(listeners)
+			return err
+		}
+	}
+
+	if IsDebugging() {
+		v.PrintRoutes(os.Stdout)
+		for _, h := range v.hosts {
+			fmt.Println("[gov-debug] host " + h.pattern)
+			h.router.PrintRoutes(os.Stdout)
+		}
+	}
+
+	errc := make(chan error, len(listeners))
+	for _, l := range listeners {
+		fmt.Println("[gov] listening on " + l.Addr().Network() + " " + l.Addr().String())
+
+		go func(l net.Listener) {
+			if config != nil {
+				errc <- v.Server.ServeTLS(l, "", "")
+			} else {
+				errc <- v.Server.Serve(l)
+			}
+		}(l)
+	}
+
+	var err error
+	for range listeners {
+		if e := <-errc; e != http.ErrServerClosed && err == nil {
+			err = e
+			v.Server.Close()
+		}
+	}
+	return err
+}
+
+// OnShutdown registers hooks that Shutdown runs, in order, once the
+// requests in flight are done or its deadline is exceeded, e.g. to close
+// database connections.
+func (v *Gov) OnShutdown(hooks ...func()) {
+	v.onShutdown = append(v.onShutdown, hooks...)
+}
+
+// Shutdown stops accepting connections, waits until the requests in flight
+// are done or ctx is done and then runs the OnShutdown hooks. It returns the
+// context error if requests were still in flight. The h2c connections are
+// waited for as well, other hijacked connections, like websockets, are not.
+func (v *Gov) Shutdown(ctx context.Context) error {
+	err := v.Server.Shutdown(ctx)
+	if err == nil {
+		err = v.waitH2C(ctx)
+	}
+
+	v.shutdownOnce.Do(func() {
+		for _, hook := range v.onShutdown {
+			hook()
+		}
+	})
+	return err
+}
+
+// RunWithGracefulShutdown is like Run, but on SIGINT or SIGTERM it shuts
+// the server down, giving the requests in flight up to timeout to complete.
+func (v *Gov) RunWithGracefulShutdown(timeout time.Duration, addr ...string) error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	return v.runUntil(quit, timeout, func() error { return v.Run(addr...) })
+}
+
+// runUntil calls run and shuts the server down when quit receives.
+func (v *Gov) runUntil(quit <-chan os.Signal, timeout time.Duration, run func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- run()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-quit:
+		fmt.Println("[gov] received " + sig.String() + ", shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := v.Shutdown(ctx); err != nil {
+		return err
+	}
+	return <-errc
+}
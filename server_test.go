@@ -0,0 +1,165 @@
+package gov
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startServer serves r on a random local port and returns its base URL
+// and the error channel of serve.
+func startServer(t *testing.T, r *Gov) (string, <-chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.serve(nil, l)
+	}()
+	return "http://" + l.Addr().String(), errc
+}
+
+func TestShutdownWaitsForRequests(t *testing.T) {
+	t.Run("HTTP/1.1", func(t *testing.T) { testShutdownWaitsForRequests(t, http.DefaultClient) })
+	t.Run("h2c", func(t *testing.T) { testShutdownWaitsForRequests(t, h2cClient()) })
+}
+
+func testShutdownWaitsForRequests(t *testing.T, client *http.Client) {
+	r := New()
+	r.H2C = true
+	started, release := make(chan struct{}), make(chan struct{})
+	r.Get("/slow", func(c *Context) {
+		close(started)
+		<-release
+		c.String("done")
+	})
+
+	var trace []string
+	r.OnShutdown(func() { trace = append(trace, "db") }, func() { trace = append(trace, "cache") })
+
+	base, errc := startServer(t, r)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		resp, err := client.Get(base + "/slow")
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		resc <- result{string(body), err}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- r.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Empty(t, trace)
+
+	close(release)
+	res := <-resc
+	assert.NoError(t, res.err)
+	assert.Equal(t, "done\n", res.body)
+	client.CloseIdleConnections()
+	assert.NoError(t, <-shutdown)
+	assert.NoError(t, <-errc)
+	assert.Equal(t, []string{"db", "cache"}, trace)
+
+	_, err := client.Get(base + "/slow")
+	assert.Error(t, err)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	r := New()
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	r.Get("/stuck", func(c *Context) {
+		close(started)
+		<-release
+	})
+	hooked := false
+	r.OnShutdown(func() { hooked = true })
+
+	base, errc := startServer(t, r)
+	go http.Get(base + "/stuck")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, r.Shutdown(ctx))
+	assert.True(t, hooked)
+	assert.NoError(t, <-errc)
+}
+
+func TestServerConfiguration(t *testing.T) {
+	r := New()
+	r.Server.MaxHeaderBytes = 1 << 10
+	r.Get("/", func(c *Context) {})
+
+	base, _ := startServer(t, r)
+	defer r.Shutdown(context.Background())
+
+	req, _ := http.NewRequest("GET", base+"/", nil)
+	req.Header.Set("X-Big", strings.Repeat("x", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
+
+func TestRunUntilSignal(t *testing.T) {
+	r := New()
+	r.Get("/", func(c *Context) {})
+	hooked := false
+	r.OnShutdown(func() { hooked = true })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quit := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.runUntil(quit, time.Second, func() error { return r.serve(nil, l) })
+	}()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	quit <- syscall.SIGTERM
+	assert.NoError(t, <-done)
+	assert.True(t, hooked)
+}
+
+func TestRunUntilServeError(t *testing.T) {
+	r := New()
+	err := r.runUntil(make(chan os.Signal), time.Second, func() error { return r.Run("127.0.0.1:-1") })
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package gov
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// onlyFilesFS hides directories that have no index.html, so directory
+// listings are never served.
+type onlyFilesFS struct {
+	fs http.FileSystem
+}
+
+func (fs onlyFilesFS) Open(name string) (http.File, error) {
+	f, err := fs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		index, err := fs.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+	return f, nil
+}
+
+// Dir returns an http.FileSystem serving the files below root. Directories
+// are only listed when listDirectory is true; otherwise a directory without
+// an index.html is reported as not found.
+func Dir(root string, listDirectory bool) http.FileSystem {
+	fs := http.Dir(root)
+	if listDirectory {
+		return fs
+	}
+	return onlyFilesFS{fs}
+}
+
+// Static serves the files below the root directory at prefix, without
+// directory listings. Use StaticFS for other file systems.
+func (r *Router) Static(prefix, root string) {
+	r.StaticFS(prefix, Dir(root, false))
+}
+
+// StaticFS serves fs at prefix for GET and HEAD requests.
+func (r *Router) StaticFS(prefix string, fs http.FileSystem) {
+	staticFS(r, joinPaths("", prefix), nil, fs)
+}
+
+// StaticFile serves a single file at relativePath for GET and HEAD
+// requests.
+func (r *Router) StaticFile(relativePath, filepath string) {
+	staticFile(r, joinPaths("", relativePath), nil, filepath)
+}
+
+func (g *RouterGroup) Static(prefix, root string) {
+	g.StaticFS(prefix, Dir(root, false))
+}
+
+func (g *RouterGroup) StaticFS(prefix string, fs http.FileSystem) {
+	staticFS(g.router, joinPaths(g.prefix, prefix), g.handlers, fs)
+}
+
+func (g *RouterGroup) StaticFile(relativePath, filepath string) {
+	staticFile(g.router, joinPaths(g.prefix, relativePath), g.handlers, filepath)
+}
+
+func staticFile(r *Router, p string, handlers HandlerChain, filepath string) {
+	if strings.ContainsAny(p, ":*") {
+		panic("URL parameters can not be used when serving a static file")
+	}
+
+	hs := combineHandlers(handlers, HandlerChain{func(c *Context) {
+		c.WriteFile(filepath)
+	}})
+	r.add(http.MethodGet, p, hs...)
+	r.add(http.MethodHead, p, hs...)
+}
+
+func staticFS(r *Router, prefix string, handlers HandlerChain, fs http.FileSystem) {
+	if strings.ContainsAny(prefix, ":*") {
+		panic("URL parameters can not be used when serving a static folder")
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	fileServer := http.FileServer(fs)
+
+	hs := combineHandlers(handlers, HandlerChain{func(c *Context) {
+		file, _ := c.params.Get("filepath")
+		if !isSafeFilePath(file) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		req := new(http.Request)
+		*req = *c.Request
+		u := *c.Request.URL
+		u.Path = file
+		u.RawPath = ""
+		req.URL = &u
+		fileServer.ServeHTTP(c.Response, req)
+	}})
+
+	r.add(http.MethodGet, prefix+"/*filepath", hs...)
+	r.add(http.MethodHead, prefix+"/*filepath", hs...)
+}
+
+// isSafeFilePath rejects paths that could escape the served directory.
+func isSafeFilePath(p string) bool {
+	if strings.ContainsAny(p, "\\\x00") {
+		return false
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return true
+}
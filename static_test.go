@@ -0,0 +1,122 @@
+package gov
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStaticDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gov-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"app.js":          "console.log('gov')",
+		"css/app.css":     "body {}",
+		"docs/index.html": "<h1>docs</h1>",
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		assert.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+	dir := newStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.Static("/assets", dir)
+
+	w := performRequest(r, "GET", "/assets/app.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console.log('gov')", w.Body.String())
+
+	w = performRequest(r, "GET", "/assets/css/app.css")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body {}", w.Body.String())
+
+	w = performRequest(r, "GET", "/assets/docs/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>docs</h1>", w.Body.String())
+
+	w = performRequest(r, "GET", "/assets/missing.js")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(r, "GET", "/assets/css/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(r, "POST", "/assets/app.js")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestStaticHead(t *testing.T) {
+	dir := newStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.Static("/assets", dir)
+
+	w := performRequest(r, "HEAD", "/assets/app.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "18", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestStaticPathTraversal(t *testing.T) {
+	dir := newStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.Group("/v1").Static("/assets", filepath.Join(dir, "css"))
+
+	for _, path := range []string{"/v1/assets/../app.js", "/v1/assets/..%2fapp.js", "/v1/assets/..\\app.js"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "gov", path)
+	}
+}
+
+func TestStaticFSDirectoryListing(t *testing.T) {
+	dir := newStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.StaticFS("/files", Dir(dir, true))
+
+	w := performRequest(r, "GET", "/files/css/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "app.css")
+}
+
+func TestStaticFile(t *testing.T) {
+	dir := newStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.StaticFile("/favicon.js", filepath.Join(dir, "app.js"))
+
+	w := performRequest(r, "GET", "/favicon.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console.log('gov')", w.Body.String())
+
+	w = performRequest(r, "HEAD", "/favicon.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	assert.Panics(t, func() { r.StaticFile("/files/:name", "x") })
+	assert.Panics(t, func() { r.Static("/files/*x", dir) })
+}
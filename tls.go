@@ -0,0 +1,110 @@
+package gov
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"time"
+)
+
+// RunTLS is like Run over TLS, with the certificate and key read from PEM
+// files. Settings of Server.TLSConfig, like MinVersion, are kept.
+func (v *Gov) RunTLS(addr, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{}
+	if v.Server.TLSConfig != nil {
+		config = v.Server.TLSConfig.Clone()
+	}
+	config.Certificates = append(config.Certificates, cert)
+	return v.RunWithTLSConfig(addr, config)
+}
+
+// RunWithTLSConfig is like Run over TLS with the given configuration, which
+// has to provide the server certificate. Setting ClientAuth and ClientCAs
+// enables mutual TLS; the verified client certificate is available from
+// Context.PeerCertificate.
+func (v *Gov) RunWithTLSConfig(addr string, config *tls.Config) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return v.serve(config, l)
+}
+
+// RunDevTLS is like RunTLS on addr, ":9000" by default, with a self-signed
+// certificate for localhost generated in memory, for local HTTPS testing.
+// Clients do not trust the certificate unless told to, and it refuses to
+// run in release mode.
+func (v *Gov) RunDevTLS(addr ...string) error {
+	if Mode() == ReleaseMode {
+		return errors.New("gov: RunDevTLS is not allowed in release mode")
+	}
+
+	cert, err := SelfSignedCertificate("localhost", "127.0.0.1", "::1")
+	if err != nil {
+		return err
+	}
+	address := ":9000"
+	if len(addr) > 0 {
+		address = addr[0]
+	}
+	return v.RunWithTLSConfig(address, &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+// SelfSignedCertificate generates a certificate valid for a year for hosts,
+// which are DNS names or IP addresses. It can authenticate servers and
+// clients, and can be added to a pool of roots to be trusted.
+func SelfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"gov development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if len(hosts) > 0 {
+		template.Subject.CommonName = hosts[0]
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,141 @@
+package gov
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tlsClient(roots *x509.Certificate, certs ...tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(roots)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, Certificates: certs},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestRunWithTLSConfigMutual(t *testing.T) {
+	serverCert, err := SelfSignedCertificate("127.0.0.1")
+	assert.NoError(t, err)
+	clientCert, err := SelfSignedCertificate("client-a")
+	assert.NoError(t, err)
+	strangerCert, err := SelfSignedCertificate("stranger")
+	assert.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	r := New()
+	r.Get("/whoami", func(c *Context) {
+		name := "anonymous"
+		if cert := c.PeerCertificate(); cert != nil {
+			name = cert.Subject.CommonName
+		}
+		c.String(name + " " + c.Request.Proto)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go r.serve(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs,
+	}, l)
+	defer r.Shutdown(context.Background())
+	url := "https://" + l.Addr().String() + "/whoami"
+
+	resp, err := tlsClient(serverCert.Leaf, clientCert).Get(url)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "client-a HTTP/2.0\n", readBody(t, resp))
+	}
+
+	resp, err = tlsClient(serverCert.Leaf).Get(url)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "anonymous HTTP/2.0\n", readBody(t, resp))
+	}
+
+	// a certificate the server does not trust fails the handshake
+	stranger := tlsClient(serverCert.Leaf)
+	stranger.Transport.(*http.Transport).TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &strangerCert, nil
+	}
+	_, err = stranger.Get(url)
+	assert.Error(t, err)
+}
+
+func TestRunTLS(t *testing.T) {
+	cert, err := SelfSignedCertificate("localhost", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, cert.Leaf.DNSNames)
+	assert.Len(t, cert.Leaf.IPAddresses, 1)
+
+	dir, err := ioutil.TempDir("", "gov-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	assert.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600))
+
+	r := New()
+	r.Server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	r.Get("/", func(c *Context) { c.String("secure") })
+
+	assert.Error(t, r.RunTLS("127.0.0.1:0", certFile, filepath.Join(dir, "missing.pem")))
+
+	// reserve a free port for RunTLS
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- r.RunTLS(addr, certFile, keyFile)
+	}()
+	defer r.Shutdown(context.Background())
+
+	client := tlsClient(cert.Leaf)
+	for i := 0; i < 50; i++ {
+		var resp *http.Response
+		if resp, err = client.Get("https://" + addr + "/"); err == nil {
+			assert.Equal(t, "secure\n", readBody(t, resp))
+			assert.Equal(t, uint16(tls.VersionTLS12), r.Server.TLSConfig.MinVersion)
+			return
+		}
+		select {
+		case err := <-errc:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal(err)
+}
+
+func TestRunDevTLSReleaseMode(t *testing.T) {
+	defer SetMode(Mode())
+	SetMode(ReleaseMode)
+
+	assert.Error(t, New().RunDevTLS("127.0.0.1:0"))
+}
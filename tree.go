@@ -0,0 +1,424 @@
+package gov
+
+import (
+	"strings"
+)
+
+type nodeType uint8
+
+const (
+	static nodeType = iota
+	root
+	param
+	catchAll
+)
+
+// node is a vertex of a prefix-compressed radix tree. Static nodes hold a
+// shared path prefix and index their static children by first byte, so
+// looking up a static route never allocates. Wildcards always span whole
+// segments: a param node ("/:name") captures one segment and a catch-all
+// node ("/*name") captures the rest of the path. A param may carry a
+// constraint (":id<int>", ":name<[a-z]+>") the segment has to satisfy.
+//
+// When several children could match, static children win over the param
+// children, which win over the catch-all child (see getValue). Constrained
+// params are tried before the unconstrained one, in registration order.
+type node struct {
+	path          string
+	nType         nodeType
+	name          string
+	indices       string
+	children      []*node
+	paramChildren []*node
+	catchAll      *node
+	check         func(string) bool
+	handlers      HandlerChain
+	fullPath      string
+	site          string
+
+	// origin and originSite record the route that introduced a wildcard
+	origin     string
+	originSite string
+}
+
+type nodeValue struct {
+	handlers HandlerChain
+	params   Params
+	fullPath string
+}
+
+func longestCommonPrefix(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// isWildcardStart reports whether path, a suffix of fullPath, starts with
+// a ':' or '*' that opens a segment.
+func isWildcardStart(path, fullPath string) bool {
+	if path[0] != ':' && path[0] != '*' {
+		return false
+	}
+	i := len(fullPath) - len(path)
+	return i > 0 && fullPath[i-1] == '/'
+}
+
+// wildcardIndex returns the index of the first wildcard in path, or -1.
+func wildcardIndex(path, fullPath string) int {
+	for i := 0; i < len(path); i++ {
+		if isWildcardStart(path[i:], fullPath) {
+			return i
+		}
+	}
+	return -1
+}
+
+// countParams returns the number of wildcards in path.
+func countParams(path string) int {
+	n := 0
+	for i := 1; i < len(path); i++ {
+		if (path[i] == ':' || path[i] == '*') && path[i-1] == '/' {
+			n++
+		}
+	}
+	return n
+}
+
+func validateWildcardName(seg, name string) *RouteError {
+	if name == "" {
+		return &RouteError{Reason: "wildcards must be named with a non-empty name"}
+	}
+	if strings.ContainsAny(name, ":*") {
+		return &RouteError{Reason: "only one wildcard per path segment is allowed, has: '" + seg + "'"}
+	}
+	if strings.ContainsAny(name, "<>") {
+		return &RouteError{Reason: "malformed constraint in '" + seg + "'"}
+	}
+	return nil
+}
+
+// registration is a route on its way into the tree.
+type registration struct {
+	path     string
+	handlers HandlerChain
+	site     string
+}
+
+// clone returns a deep copy of the tree rooted at n. The handlers and
+// constraint checks are shared, they are never modified.
+func (n *node) clone() *node {
+	c := *n
+
+	if n.children != nil {
+		c.children = make([]*node, len(n.children))
+		for i, child := range n.children {
+			c.children[i] = child.clone()
+		}
+	}
+	if n.paramChildren != nil {
+		c.paramChildren = make([]*node, len(n.paramChildren))
+		for i, child := range n.paramChildren {
+			c.paramChildren[i] = child.clone()
+		}
+	}
+	if n.catchAll != nil {
+		c.catchAll = n.catchAll.clone()
+	}
+	return &c
+}
+
+// walk calls fn for n and then for its descendants, in the order they are
+// matched.
+func (n *node) walk(fn func(*node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+	for _, child := range n.paramChildren {
+		child.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
+
+// addRoute adds a route to the tree rooted at n. Registering a path twice,
+// or a wildcard that makes an existing one unreachable, is a conflict.
+func (n *node) addRoute(path string, handlers HandlerChain, site string) *RouteError {
+	if path == "" || path[0] != '/' {
+		return &RouteError{Reason: "path must begin with '/'"}
+	}
+
+	n.nType = root
+	return n.insert(path, &registration{path: path, handlers: handlers, site: site})
+}
+
+// setHandlers stores the handlers of r on n unless n already has some.
+func (n *node) setHandlers(r *registration) *RouteError {
+	if n.handlers != nil {
+		return &RouteError{
+			Reason:       "handlers are already registered for this path",
+			Conflict:     n.fullPath,
+			ConflictSite: n.site,
+		}
+	}
+
+	n.handlers = r.handlers
+	n.fullPath = r.path
+	n.site = r.site
+	return nil
+}
+
+func (n *node) insert(path string, r *registration) *RouteError {
+walk:
+	for {
+		i := longestCommonPrefix(path, n.path)
+
+		// split the node so that n.path becomes the shared prefix
+		if i < len(n.path) {
+			child := &node{
+				path:          n.path[i:],
+				nType:         static,
+				indices:       n.indices,
+				children:      n.children,
+				paramChildren: n.paramChildren,
+				catchAll:      n.catchAll,
+				handlers:      n.handlers,
+				fullPath:      n.fullPath,
+				site:          n.site,
+			}
+
+			n.path = n.path[:i]
+			n.indices = child.path[:1]
+			n.children = []*node{child}
+			n.paramChildren = nil
+			n.catchAll = nil
+			n.handlers = nil
+			n.fullPath = ""
+			n.site = ""
+		}
+
+		path = path[i:]
+		if path == "" {
+			return n.setHandlers(r)
+		}
+
+		if isWildcardStart(path, r.path) {
+			return n.insertWildcard(path, r)
+		}
+
+		if j := strings.IndexByte(n.indices, path[0]); j >= 0 {
+			n = n.children[j]
+			continue walk
+		}
+
+		return n.insertStatic(path, r)
+	}
+}
+
+// insertStatic adds a new static child to n holding path up to its first
+// wildcard.
+func (n *node) insertStatic(path string, r *registration) *RouteError {
+	end := wildcardIndex(path, r.path)
+	child := &node{nType: static}
+	if end < 0 {
+		child.path = path
+	} else {
+		child.path = path[:end]
+		if err := child.insertWildcard(path[end:], r); err != nil {
+			return err
+		}
+	}
+
+	n.indices += path[:1]
+	n.children = append(n.children, child)
+
+	if end < 0 {
+		return child.setHandlers(r)
+	}
+	return nil
+}
+
+// insertWildcard attaches the wildcard segment at the start of path to n.
+func (n *node) insertWildcard(path string, r *registration) *RouteError {
+	if path[0] == '*' {
+		if i := strings.IndexByte(path, '/'); i >= 0 {
+			return &RouteError{Reason: "catch-all routes are only allowed at the end of the path, got '" + path[:i] + "' followed by '" + path[i:] + "'"}
+		}
+
+		name := path[1:]
+		if err := validateWildcardName(path, name); err != nil {
+			return err
+		}
+		if c := n.catchAll; c != nil && c.path != path {
+			return &RouteError{
+				Reason:       "catch-all '" + path + "' conflicts with existing catch-all '" + c.path + "'",
+				Conflict:     c.origin,
+				ConflictSite: c.originSite,
+			}
+		}
+		if n.catchAll == nil {
+			n.catchAll = &node{path: path, nType: catchAll, name: name, origin: r.path, originSite: r.site}
+		}
+
+		return n.catchAll.setHandlers(r)
+	}
+
+	end := wildcardEnd(path)
+	seg := path[:end]
+	name, constraint := splitParam(seg)
+	if err := validateWildcardName(seg, name); err != nil {
+		return err
+	}
+
+	var p *node
+	for _, child := range n.paramChildren {
+		if child.path == seg {
+			p = child
+			break
+		}
+		if _, c := splitParam(child.path); c == constraint {
+			return &RouteError{
+				Reason:       "param '" + seg + "' conflicts with existing param '" + child.path + "' at the same position",
+				Conflict:     child.origin,
+				ConflictSite: child.originSite,
+			}
+		}
+	}
+
+	isNew := p == nil
+	if isNew {
+		p = &node{path: seg, nType: param, name: name, origin: r.path, originSite: r.site}
+		if constraint != "" {
+			check, err := compileConstraint(constraint)
+			if err != nil {
+				return &RouteError{Reason: "invalid constraint in '" + seg + "': " + err.Error()}
+			}
+			p.check = check
+		}
+	}
+
+	var err *RouteError
+	path = path[end:]
+	if path == "" {
+		err = p.setHandlers(r)
+	} else if j := strings.IndexByte(p.indices, path[0]); j >= 0 {
+		err = p.children[j].insert(path, r)
+	} else {
+		err = p.insertStatic(path, r)
+	}
+
+	if err == nil && isNew {
+		n.addParamChild(p)
+	}
+	return err
+}
+
+// addParamChild adds p to the param children of n, keeping the
+// unconstrained param last.
+func (n *node) addParamChild(p *node) {
+	n.paramChildren = append(n.paramChildren, p)
+	if p.check == nil {
+		return
+	}
+
+	for i := len(n.paramChildren) - 1; i > 0 && n.paramChildren[i-1].check == nil; i-- {
+		n.paramChildren[i], n.paramChildren[i-1] = n.paramChildren[i-1], n.paramChildren[i]
+	}
+}
+
+// hasPrefix is strings.HasPrefix for a node path.
+func (n *node) hasPrefix(path string) bool {
+	return len(path) >= len(n.path) && path[:len(n.path)] == n.path
+}
+
+// getValue returns the handlers registered for path. Captured wildcards are
+// appended to params, which lets callers reuse a buffer across lookups.
+//
+// Candidates are tried depth first in the order static, param, catch-all.
+// When a branch dead-ends further down, matching backtracks to the next
+// candidate of the deepest node that still has one, so "/users/new/posts"
+// reaches "/users/:id/posts" even if "/users/new/edit" is registered too.
+func (n *node) getValue(path string, params Params) (value nodeValue) {
+	value.params = params
+	if !n.match(path, path, &value) {
+		return nodeValue{}
+	}
+	return
+}
+
+// match reports whether path, a suffix of full, matches a route below n.
+func (n *node) match(path, full string, value *nodeValue) bool {
+	if !n.hasPrefix(path) {
+		return false
+	}
+	path = path[len(n.path):]
+
+	if path == "" {
+		if n.handlers != nil {
+			value.handlers = n.handlers
+			value.fullPath = n.fullPath
+			return true
+		}
+	} else {
+		if j := strings.IndexByte(n.indices, path[0]); j >= 0 && n.children[j].match(path, full, value) {
+			return true
+		}
+
+		if len(n.paramChildren) > 0 {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+
+			if end > 0 {
+				seg := path[:end]
+				for _, p := range n.paramChildren {
+					if p.check != nil && !p.check(seg) {
+						continue
+					}
+
+					value.params = append(value.params, Param{Key: p.name, Value: seg})
+					if p.matchParam(path[end:], full, value) {
+						return true
+					}
+					value.params = value.params[:len(value.params)-1]
+				}
+			}
+		}
+	}
+
+	// the catch-all value keeps the slash that precedes it
+	if c := n.catchAll; c != nil {
+		value.handlers = c.handlers
+		value.fullPath = c.fullPath
+		value.params = append(value.params, Param{Key: c.name, Value: full[len(full)-len(path)-1:]})
+		return true
+	}
+	return false
+}
+
+// matchParam continues matching below the param node p with the path that
+// follows the captured segment.
+func (p *node) matchParam(path, full string, value *nodeValue) bool {
+	if path == "" {
+		if p.handlers == nil {
+			return false
+		}
+		value.handlers = p.handlers
+		value.fullPath = p.fullPath
+		return true
+	}
+
+	if j := strings.IndexByte(p.indices, path[0]); j >= 0 {
+		return p.children[j].match(path, full, value)
+	}
+	return false
+}
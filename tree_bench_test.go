@@ -0,0 +1,284 @@
+package gov
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// legacyRouter is the segment tree router used before the radix tree: add,
+// insert2, methodTrees.get and Gov.handle are copied verbatim from it, with
+// only their types renamed. It only lives on in the benchmarks as a
+// baseline.
+type legacyRouter struct {
+	trees legacyTrees
+}
+
+type legacyTree struct {
+	method string
+	root   *legacyNode
+}
+
+type legacyTrees []legacyTree
+
+type legacyNode struct {
+	path     string
+	handlers HandlerChain
+	children []*legacyNode
+	fullpath string
+	nType    nodeType
+	name     string
+}
+
+type legacyValue struct {
+	handlers HandlerChain
+	params   Params
+	fullPath string
+}
+
+func legacyInsert2(root *legacyNode, m string, p []string, hs ...HandlerFunc) {
+	for _, n := range root.children {
+
+		if n.path == p[0] {
+
+			if len(p[1:]) == 0 {
+				n.handlers = hs
+			} else {
+				legacyInsert2(n, m, p[1:], hs...)
+			}
+
+			return
+		}
+	}
+
+	var n *legacyNode
+	if strings.HasPrefix(p[0], ":") {
+		n = &legacyNode{
+			path:     p[0],
+			handlers: hs,
+			children: []*legacyNode{},
+			nType:    param,
+			name:     p[0][1:],
+		}
+	} else {
+		n = &legacyNode{
+			path:     p[0],
+			handlers: hs,
+			children: []*legacyNode{},
+		}
+	}
+
+	root.children = append(root.children, n)
+
+	if len(p[1:]) > 0 {
+		legacyInsert2(n, m, p[1:], hs...)
+	}
+}
+
+func (r *legacyRouter) add(m, path string, hs ...HandlerFunc) {
+
+	root := r.trees.get(m)
+
+	if root == nil {
+		root = &legacyNode{
+			path:     "/",
+			handlers: HandlerChain{},
+			children: []*legacyNode{},
+			fullpath: "/",
+		}
+
+		r.trees = append(r.trees, legacyTree{method: m, root: root})
+	}
+
+	if root.path == path {
+		root.handlers = hs
+	} else {
+		segs := strings.Split(path, "/")
+
+		legacyInsert2(root, m, segs[1:], hs...)
+	}
+}
+
+func (trees legacyTrees) get(method string) *legacyNode {
+	for _, tree := range trees {
+		if tree.method == method {
+			return tree.root
+		}
+	}
+	return nil
+}
+
+func (v *legacyRouter) handle(m, path string) (ret legacyValue) {
+	r := v.trees.get(m)
+
+	if len(path) > len(r.path) {
+		// not this node
+
+		p := legacySplitBy('/', path)
+
+		children := r.children
+	GOV:
+		for {
+			for _, child := range children {
+				if child.path == p[0] {
+					p = p[1:]
+					if len(p) > 0 {
+						children = child.children
+						ret.fullPath += "/" + child.path
+						continue GOV
+					}
+					ret.handlers = child.handlers
+					return
+				}
+
+				if child.nType == param {
+					ret.params = append(ret.params, Param{
+						Key:   child.name,
+						Value: p[0],
+					})
+					p = p[1:]
+					if len(p) > 0 {
+						ret.fullPath += "/" + child.path
+						children = child.children
+						continue GOV
+					}
+
+					ret.handlers = child.handlers
+					return
+				}
+
+			}
+
+			return
+		}
+
+	}
+	ret.handlers = r.handlers
+	return
+}
+
+func legacySplitBy(sperator rune, str string) []string {
+	return strings.FieldsFunc(str, func(r rune) bool {
+		return r == sperator
+	})
+}
+
+// benchRoutes mimics a large REST API with 600 endpoints.
+func benchRoutes() []string {
+	routes := make([]string, 0, 600)
+	for i := 0; i < 120; i++ {
+		resource := "/api/v1/resource" + strconv.Itoa(i)
+		routes = append(routes,
+			resource,
+			resource+"/search",
+			resource+"/:id",
+			resource+"/:id/history",
+			resource+"/:id/owners/:owner",
+		)
+	}
+	return routes
+}
+
+var (
+	benchStaticPath = "/api/v1/resource97/search"
+	benchParamPath  = "/api/v1/resource97/42/owners/gopher"
+)
+
+// newBenchTrees returns the router and the legacy router with benchRoutes,
+// both looked up through their method trees.
+func newBenchTrees() (*Router, *legacyRouter) {
+	tree := &Router{}
+	legacy := &legacyRouter{}
+
+	for _, route := range benchRoutes() {
+		tree.add("GET", route, fakeHandler(route)...)
+		legacy.add("GET", route, fakeHandler(route)...)
+	}
+
+	return tree, legacy
+}
+
+func BenchmarkTreeStatic(b *testing.B) {
+	tree, _ := newBenchTrees()
+	params := make(Params, 0, 4)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.handle("GET", benchStaticPath, params[:0])
+	}
+}
+
+func BenchmarkLegacyTreeStatic(b *testing.B) {
+	_, legacy := newBenchTrees()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		legacy.handle("GET", benchStaticPath)
+	}
+}
+
+func BenchmarkTreeParam(b *testing.B) {
+	tree, _ := newBenchTrees()
+	params := make(Params, 0, 4)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.handle("GET", benchParamPath, params[:0])
+	}
+}
+
+func BenchmarkLegacyTreeParam(b *testing.B) {
+	_, legacy := newBenchTrees()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		legacy.handle("GET", benchParamPath)
+	}
+}
+
+func BenchmarkTreeAllRoutes(b *testing.B) {
+	tree, _ := newBenchTrees()
+	routes := benchRoutes()
+	params := make(Params, 0, 4)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, route := range routes {
+			tree.handle("GET", route, params[:0])
+		}
+	}
+}
+
+func BenchmarkLegacyTreeAllRoutes(b *testing.B) {
+	_, legacy := newBenchTrees()
+	routes := benchRoutes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, route := range routes {
+			legacy.handle("GET", route)
+		}
+	}
+}
+
+func TestLegacyTreeMatchesRadixTree(t *testing.T) {
+	tree, legacy := newBenchTrees()
+
+	for _, path := range []string{benchStaticPath, benchParamPath, "/api/v1/resource3/7", "/api/v1/missing"} {
+		value := tree.handle("GET", path, nil)
+		old := legacy.handle("GET", path)
+
+		if (value.handlers == nil) != (old.handlers == nil) {
+			t.Fatalf("lookup mismatch for %s", path)
+		}
+		if len(value.params) != len(old.params) {
+			t.Fatalf("params mismatch for %s: %v vs %v", path, value.params, old.params)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package gov
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeHandler(val string) HandlerChain {
+	return HandlerChain{func(c *Context) {
+		c.Set("route", val)
+	}}
+}
+
+type testRequest struct {
+	path     string
+	found    bool
+	fullPath string
+	params   Params
+}
+
+func checkRequests(t *testing.T, tree *node, requests []testRequest) {
+	for _, request := range requests {
+		value := tree.getValue(request.path, nil)
+
+		if !request.found {
+			assert.Nil(t, value.handlers, "handlers for %s", request.path)
+			continue
+		}
+
+		if assert.NotNil(t, value.handlers, "handlers for %s", request.path) {
+			assert.Equal(t, request.fullPath, value.fullPath, "route for %s", request.path)
+			assert.Equal(t, request.params, value.params, "params for %s", request.path)
+		}
+	}
+}
+
+func TestTreeAddAndGet(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/",
+		"/hi",
+		"/contact",
+		"/co",
+		"/c",
+		"/a",
+		"/ab",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/α",
+		"/β",
+	}
+	for _, route := range routes {
+		if err := tree.addRoute(route, fakeHandler(route), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkRequests(t, tree, []testRequest{
+		{"/a", true, "/a", nil},
+		{"/", true, "/", nil},
+		{"/hi", true, "/hi", nil},
+		{"/contact", true, "/contact", nil},
+		{"/co", true, "/co", nil},
+		{"/con", false, "", nil},
+		{"/cona", false, "", nil},
+		{"/no", false, "", nil},
+		{"/ab", true, "/ab", nil},
+		{"/doc/go1.html", true, "/doc/go1.html", nil},
+		{"/α", true, "/α", nil},
+		{"/β", true, "/β", nil},
+	})
+}
+
+func TestTreeWildcard(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/files/:dir/*filepath",
+		"/info/:user/public",
+		"/info/:user/project/:project",
+	}
+	for _, route := range routes {
+		if err := tree.addRoute(route, fakeHandler(route), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkRequests(t, tree, []testRequest{
+		{"/", true, "/", nil},
+		{"/cmd/test/", true, "/cmd/:tool/", Params{{"tool", "test"}}},
+		{"/cmd/test", false, "", nil},
+		{"/cmd/test/3", true, "/cmd/:tool/:sub", Params{{"tool", "test"}, {"sub", "3"}}},
+		{"/src/", true, "/src/*filepath", Params{{"filepath", "/"}}},
+		{"/src/some/file.png", true, "/src/*filepath", Params{{"filepath", "/some/file.png"}}},
+		{"/search/", true, "/search/", nil},
+		{"/search/someth!ng+in+ünìcodé", true, "/search/:query", Params{{"query", "someth!ng+in+ünìcodé"}}},
+		{"/search/someth!ng+in+ünìcodé/", false, "", nil},
+		{"/user_gopher", false, "", nil},
+		{"/user_:name", true, "/user_:name", nil},
+		{"/files/js/inc/framework.js", true, "/files/:dir/*filepath", Params{{"dir", "js"}, {"filepath", "/inc/framework.js"}}},
+		{"/info/gordon/public", true, "/info/:user/public", Params{{"user", "gordon"}}},
+		{"/info/gordon/project/go", true, "/info/:user/project/:project", Params{{"user", "gordon"}, {"project", "go"}}},
+	})
+}
+
+func TestTreeStaticBeforeParam(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts",
+		"/static/index",
+		"/static/*filepath",
+	}
+	for _, route := range routes {
+		if err := tree.addRoute(route, fakeHandler(route), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkRequests(t, tree, []testRequest{
+		{"/users/new", true, "/users/new", nil},
+		{"/users/nancy", true, "/users/:id", Params{{"id", "nancy"}}},
+		{"/users/ne", true, "/users/:id", Params{{"id", "ne"}}},
+		{"/static/index", true, "/static/index", nil},
+		{"/static/ind", true, "/static/*filepath", Params{{"filepath", "/ind"}}},
+	})
+}
+
+func TestTreeInvalidRoutes(t *testing.T) {
+	invalid := [...]string{
+		"",
+		"noslash",
+		"/user/:",
+		"/user/:id:name",
+		"/src/*",
+		"/src/*filepath/x",
+	}
+
+	for _, route := range invalid {
+		tree := &node{}
+		assert.NotNil(t, tree.addRoute(route, fakeHandler(route), ""), route)
+	}
+}
+
+func TestTreeWildcardConflicts(t *testing.T) {
+	tree := &node{}
+	assert.Nil(t, tree.addRoute("/user/:id", fakeHandler("/user/:id"), "a.go:1"))
+	assert.Nil(t, tree.addRoute("/src/*filepath", fakeHandler("/src/*filepath"), "a.go:2"))
+
+	err := tree.addRoute("/user/:name/edit", fakeHandler("x"), "b.go:1")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "/user/:id", err.Conflict)
+		assert.Equal(t, "a.go:1", err.ConflictSite)
+	}
+
+	err = tree.addRoute("/src/*rest", fakeHandler("x"), "b.go:2")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "/src/*filepath", err.Conflict)
+		assert.Equal(t, "a.go:2", err.ConflictSite)
+	}
+
+	assert.Nil(t, tree.addRoute("/user/:id/edit", fakeHandler("x"), ""))
+	assert.Nil(t, tree.addRoute("/src/:dir", fakeHandler("x"), ""))
+}
+
+func TestTreeDuplicateRoutes(t *testing.T) {
+	routes := [...]string{
+		"/",
+		"/doc/",
+		"/src/*filepath",
+		"/search/:query",
+		"/user_:name",
+		"/cmd/:tool/:sub",
+	}
+
+	tree := &node{}
+	for i, route := range routes {
+		assert.Nil(t, tree.addRoute(route, fakeHandler(route), "a.go:"+strconv.Itoa(i)))
+	}
+
+	for i, route := range routes {
+		err := tree.addRoute(route, fakeHandler(route), "b.go:1")
+		if assert.NotNil(t, err, route) {
+			assert.Equal(t, route, err.Conflict)
+			assert.Equal(t, "a.go:"+strconv.Itoa(i), err.ConflictSite)
+		}
+	}
+
+	// a failed registration leaves the tree untouched
+	checkRequests(t, tree, []testRequest{
+		{"/search/go", true, "/search/:query", Params{{"query", "go"}}},
+		{"/src/a/b", true, "/src/*filepath", Params{{"filepath", "/a/b"}}},
+	})
+}
+
+func TestTreeFailedRegistrationIsDiscarded(t *testing.T) {
+	tree := &node{}
+	assert.Nil(t, tree.addRoute("/a/:id", fakeHandler("/a/:id"), ""))
+	assert.NotNil(t, tree.addRoute("/b/:id/*x/y", fakeHandler("bad"), ""))
+	assert.NotNil(t, tree.addRoute("/a/:id/*x/y", fakeHandler("bad"), ""))
+
+	checkRequests(t, tree, []testRequest{
+		{"/b/1", false, "", nil},
+		{"/a/1", true, "/a/:id", Params{{"id", "1"}}},
+	})
+}
+
+func TestTreeBacktracking(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/users/new/edit",
+		"/users/new/settings",
+		"/users/:id/posts",
+		"/users/:id/posts/:post",
+		"/users/:id",
+		"/users/*rest",
+		"/repos/:owner/:repo/issues",
+		"/repos/:owner/settings",
+		"/repos/golang/go/pulls",
+		"/assets/app.js",
+		"/assets/*filepath",
+		"/a/b/c",
+		"/a/:x/d",
+		"/a/*all",
+	}
+	for _, route := range routes {
+		if err := tree.addRoute(route, fakeHandler(route), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		testRequest
+	}{
+		{"static wins over param", testRequest{"/users/new/edit", true, "/users/new/edit", nil}},
+		{"static wins over param at depth", testRequest{"/users/new/settings", true, "/users/new/settings", nil}},
+		{"param after static dead end", testRequest{"/users/new/posts", true, "/users/:id/posts", Params{{"id", "new"}}}},
+		{"param after partial static prefix", testRequest{"/users/ne/posts", true, "/users/:id/posts", Params{{"id", "ne"}}}},
+		{"nested params after dead end", testRequest{"/users/new/posts/7", true, "/users/:id/posts/:post", Params{{"id", "new"}, {"post", "7"}}}},
+		{"param wins over catch-all", testRequest{"/users/new", true, "/users/:id", Params{{"id", "new"}}}},
+		{"catch-all after param dead end", testRequest{"/users/new/unknown", true, "/users/*rest", Params{{"rest", "/new/unknown"}}}},
+		{"catch-all drops captured params", testRequest{"/users/42/posts/7/x", true, "/users/*rest", Params{{"rest", "/42/posts/7/x"}}}},
+		{"param siblings of static", testRequest{"/repos/golang/go/issues", true, "/repos/:owner/:repo/issues", Params{{"owner", "golang"}, {"repo", "go"}}}},
+		{"static deep branch", testRequest{"/repos/golang/go/pulls", true, "/repos/golang/go/pulls", nil}},
+		{"static inside param branch", testRequest{"/repos/golang/settings", true, "/repos/:owner/settings", Params{{"owner", "golang"}}}},
+		{"no match anywhere", testRequest{"/repos/golang/go/wiki", false, "", nil}},
+		{"static file over catch-all", testRequest{"/assets/app.js", true, "/assets/app.js", nil}},
+		{"catch-all for other files", testRequest{"/assets/app.css", true, "/assets/*filepath", Params{{"filepath", "/app.css"}}}},
+		{"static path", testRequest{"/a/b/c", true, "/a/b/c", nil}},
+		{"param after static prefix", testRequest{"/a/b/d", true, "/a/:x/d", Params{{"x", "b"}}}},
+		{"catch-all after both fail", testRequest{"/a/b/e", true, "/a/*all", Params{{"all", "/b/e"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRequests(t, tree, []testRequest{tt.testRequest})
+		})
+	}
+}
+
+func TestTreeParamConstraints(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/users/:name",
+		"/users/:id<int>",
+		"/users/:uid<uuid>",
+		"/users/:id<int>/posts",
+		"/files/:name<[a-z]+\\.txt>",
+		"/files/*filepath",
+		"/tags/:tag<[^/]+>/edit",
+	}
+	for _, route := range routes {
+		if err := tree.addRoute(route, fakeHandler(route), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkRequests(t, tree, []testRequest{
+		{"/users/42", true, "/users/:id<int>", Params{{"id", "42"}}},
+		{"/users/-7", true, "/users/:id<int>", Params{{"id", "-7"}}},
+		{"/users/3f2504e0-4f89-11d3-9a0c-0305e82c3301", true, "/users/:uid<uuid>", Params{{"uid", "3f2504e0-4f89-11d3-9a0c-0305e82c3301"}}},
+		{"/users/gopher", true, "/users/:name", Params{{"name", "gopher"}}},
+		{"/users/42/posts", true, "/users/:id<int>/posts", Params{{"id", "42"}}},
+		{"/users/gopher/posts", false, "", nil},
+		{"/files/notes.txt", true, "/files/:name<[a-z]+\\.txt>", Params{{"name", "notes.txt"}}},
+		{"/files/Notes.txt", true, "/files/*filepath", Params{{"filepath", "/Notes.txt"}}},
+		{"/files/notes.md", true, "/files/*filepath", Params{{"filepath", "/notes.md"}}},
+		{"/tags/go/edit", true, "/tags/:tag<[^/]+>/edit", Params{{"tag", "go"}}},
+	})
+}
+
+func TestTreeParamConstraintErrors(t *testing.T) {
+	tree := &node{}
+	assert.Nil(t, tree.addRoute("/users/:id<int>", fakeHandler("a"), ""))
+
+	assert.NotNil(t, tree.addRoute("/users/:uid<int>", fakeHandler("b"), ""))
+	assert.NotNil(t, tree.addRoute("/users/:id<int>", fakeHandler("b"), ""))
+	assert.NotNil(t, tree.addRoute("/posts/:id<[a-z>", fakeHandler("b"), ""))
+	assert.NotNil(t, tree.addRoute("/posts/:id<(>", fakeHandler("b"), ""))
+	assert.NotNil(t, tree.addRoute("/posts/:path<[^/]+/edit>", fakeHandler("b"), ""))
+	assert.NotNil(t, tree.addRoute("/posts/:path<a|\\x2fb>", fakeHandler("b"), ""))
+	assert.Nil(t, tree.addRoute("/users/:name", fakeHandler("c"), ""))
+	assert.NotNil(t, tree.addRoute("/users/:other", fakeHandler("d"), ""))
+}
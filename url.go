@@ -0,0 +1,116 @@
+package gov
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Route is a registered path, returned by the registration methods so the
+// route can be named.
+type Route struct {
+	Path   string
+	router *Router
+}
+
+// Name names the route for URLFor. A name can only refer to one path.
+func (rt *Route) Name(name string) *Route {
+	r := rt.router
+	if r.concurrent {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+	if path, exists := r.names[name]; exists && path != rt.Path {
+		panic("route name '" + name + "' is already used for path '" + path + "'")
+	}
+
+	if r.names == nil {
+		r.names = make(map[string]string)
+	}
+	r.names[name] = rt.Path
+	return rt
+}
+
+// URLFor builds the path of the route called name. params are key/value
+// pairs filling its wildcards, e.g. URLFor("user", "id", "42"). Values are
+// escaped; a catch-all value may contain slashes. A value not satisfying the
+// constraint of its param is an error, the path would not match the route.
+func (r *Router) URLFor(name string, params ...string) (string, error) {
+	if r.concurrent {
+		r.mu.Lock()
+	}
+	pattern, ok := r.names[name]
+	if r.concurrent {
+		r.mu.Unlock()
+	}
+	if !ok {
+		return "", fmt.Errorf("gov: no route named %q", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("gov: URLFor(%q) expects key/value pairs, got %d values", name, len(params))
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		if _, dup := values[params[i]]; dup {
+			return "", fmt.Errorf("gov: URLFor(%q) got param %q twice", name, params[i])
+		}
+		values[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	for rest := pattern; rest != ""; {
+		i := wildcardIndex(rest, pattern)
+		if i < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:i])
+		rest = rest[i:]
+
+		end := len(rest)
+		if rest[0] == ':' {
+			end = wildcardEnd(rest)
+		}
+		seg := rest[:end]
+		rest = rest[end:]
+
+		key, constraint := splitParam(seg)
+		value, ok := values[key]
+		if !ok || value == "" {
+			return "", fmt.Errorf("gov: URLFor(%q) is missing param %q", name, key)
+		}
+		delete(values, key)
+
+		if constraint != "" {
+			// the route registered, so the constraint compiles
+			check, _ := compileConstraint(constraint)
+			if !check(value) {
+				return "", fmt.Errorf("gov: URLFor(%q) param %q does not satisfy <%s>: %q", name, key, constraint, value)
+			}
+		}
+
+		if seg[0] == ':' {
+			b.WriteString(url.PathEscape(value))
+			continue
+		}
+
+		parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		b.WriteString(strings.Join(parts, "/"))
+	}
+
+	if len(values) > 0 {
+		extra := make([]string, 0, len(values))
+		for key := range values {
+			extra = append(extra, key)
+		}
+		sort.Strings(extra)
+		return "", fmt.Errorf("gov: URLFor(%q) got unknown params %q", name, extra)
+	}
+
+	return b.String(), nil
+}
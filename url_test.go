@@ -0,0 +1,65 @@
+package gov
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLFor(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(c *Context) {}).Name("user")
+	r.Put("/users/:id", func(c *Context) {}).Name("user")
+	r.Get("/static/*filepath", func(c *Context) {}).Name("static")
+	r.Group("/api").Get("/repos/:owner/:repo/", func(c *Context) {}).Name("repo")
+	r.Get("/about", func(c *Context) {}).Name("about")
+	r.Get("/files/:id<int>/:name<[^/]+\\.txt>", func(c *Context) {}).Name("file")
+
+	tests := []struct {
+		name   string
+		params []string
+		url    string
+	}{
+		{"user", []string{"id", "42"}, "/users/42"},
+		{"user", []string{"id", "a b/c?"}, "/users/a%20b%2Fc%3F"},
+		{"static", []string{"filepath", "css/app.css"}, "/static/css/app.css"},
+		{"static", []string{"filepath", "/img/a b.png"}, "/static/img/a%20b.png"},
+		{"repo", []string{"repo", "go", "owner", "golang"}, "/api/repos/golang/go/"},
+		{"about", nil, "/about"},
+		{"file", []string{"id", "7", "name", "a.txt"}, "/files/7/a.txt"},
+	}
+
+	for _, tt := range tests {
+		url, err := r.URLFor(tt.name, tt.params...)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.url, url)
+	}
+}
+
+func TestURLForErrors(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(c *Context) {}).Name("user")
+	r.Get("/x/:id<int>/:code<[a-z]{2}>", func(c *Context) {}).Name("x")
+
+	errors := map[string][]string{
+		"\"id\" does not satisfy <int>":        {"x", "id", "abc", "code", "fr"},
+		"\"code\" does not satisfy <[a-z]{2}>": {"x", "id", "1", "code", "fra"},
+		"no route named":                       {"missing"},
+		"key/value pairs":                      {"user", "id"},
+		"missing param":                        {"user", "name", "x"},
+		"missing param \"id":                   {"user", "id", ""},
+		"twice":                                {"user", "id", "1", "id", "2"},
+		"unknown params":                       {"user", "id", "1", "page", "2"},
+	}
+
+	for msg, args := range errors {
+		_, err := r.URLFor(args[0], args[1:]...)
+		if assert.Error(t, err, msg) {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+
+	assert.Panics(t, func() {
+		r.Get("/people/:id", func(c *Context) {}).Name("user")
+	})
+}
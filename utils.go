@@ -1,5 +1,19 @@
 package gov
 
+import (
+	"path"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var pkgPath = reflect.TypeOf(Router{}).PkgPath()
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
 func cleanHeaderFlags(h string) string {
 	for i, c := range h {
 		if c == ' ' || c == ';' {
@@ -9,3 +23,38 @@ func cleanHeaderFlags(h string) string {
 
 	return h
 }
+
+// callerSite returns "file:line" of the first caller outside this package,
+// which is where a route was registered from.
+func callerSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+		inPkg := strings.HasPrefix(frame.Function, pkgPath+".") && !strings.HasSuffix(frame.File, "_test.go")
+		if !inPkg {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// cleanPath is path.Clean that keeps a trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if len(p) > 1 && p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// toggleTrailingSlash adds a trailing slash to p or removes it.
+func toggleTrailingSlash(p string) string {
+	if len(p) > 1 && p[len(p)-1] == '/' {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
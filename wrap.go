@@ -0,0 +1,83 @@
+package gov
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WrapF turns an http.HandlerFunc into a HandlerFunc.
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		f(c.Response, c.Request)
+	}
+}
+
+// WrapH turns an http.Handler into a HandlerFunc.
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Response, c.Request)
+	}
+}
+
+// Mount serves prefix and everything below it with h for all methods. The
+// prefix is stripped from the request path, so h sees "/debug/pprof/heap"
+// mounted at "/debug" as "/pprof/heap". Another *Gov can be mounted as a
+// sub-application with its own routes and middlewares.
+func (r *Router) Mount(prefix string, h http.Handler) {
+	mount(r, joinPaths("", prefix), nil, h)
+}
+
+// Mount is like Router.Mount with the prefix relative to the group. The
+// group middlewares run before h.
+func (g *RouterGroup) Mount(prefix string, h http.Handler) {
+	mount(g.router, joinPaths(g.prefix, prefix), g.handlers, h)
+}
+
+func mount(r *Router, prefix string, handlers HandlerChain, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	hs := combineHandlers(handlers, HandlerChain{stripPrefix(prefix, h)})
+
+	for _, m := range anyMethods {
+		if prefix != "" {
+			r.add(m, prefix, hs...)
+		}
+		r.add(m, prefix+"/*path", hs...)
+	}
+}
+
+// mountPrefixKey is the request context key of the prefixes stripped by the
+// mounts a request went through.
+type mountPrefixKey struct{}
+
+// mountPrefix returns the prefixes stripped from the request path, which the
+// redirects of a mounted Gov have to keep.
+func mountPrefix(r *http.Request) string {
+	prefix, _ := r.Context().Value(mountPrefixKey{}).(string)
+	return prefix
+}
+
+// stripPrefix is http.StripPrefix that serves the prefix itself as "/".
+func stripPrefix(prefix string, h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		r := c.Request
+
+		u := new(url.URL)
+		*u = *r.URL
+		u.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		if r.URL.RawPath != "" {
+			u.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+			if u.RawPath == "" {
+				u.RawPath = "/"
+			}
+		}
+
+		r2 := r.WithContext(context.WithValue(r.Context(), mountPrefixKey{}, mountPrefix(r)+prefix))
+		r2.URL = u
+		h.ServeHTTP(c.Response, r2)
+	}
+}
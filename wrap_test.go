@@ -0,0 +1,99 @@
+package gov
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapFAndWrapH(t *testing.T) {
+	r := New()
+	r.Get("/f", WrapF(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("f " + req.URL.Path))
+	}))
+	r.Get("/h", WrapH(http.NotFoundHandler()))
+
+	w := performRequest(r, "GET", "/f")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "f /f", w.Body.String())
+
+	w = performRequest(r, "GET", "/h")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMountHandler(t *testing.T) {
+	r := New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Method + " " + req.URL.Path + " " + req.URL.RawQuery))
+	})
+	r.Mount("/legacy/", mux)
+
+	tests := map[string]string{
+		"/legacy":             "GET / ",
+		"/legacy/":            "GET / ",
+		"/legacy/a/b?x=1":     "GET /a/b x=1",
+		"/legacy/users/:id/x": "GET /users/:id/x ",
+	}
+	for path, body := range tests {
+		w := performRequest(r, "GET", path)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Equal(t, body, w.Body.String(), path)
+	}
+
+	w := performRequest(r, "DELETE", "/legacy/a")
+	assert.Equal(t, "DELETE /a ", w.Body.String())
+
+	w = performRequest(r, "GET", "/legacyx")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMountSubApplication(t *testing.T) {
+	trace := ""
+
+	sub := New()
+	sub.Use(func(c *Context) { trace += "sub " })
+	sub.Get("/users/:id", func(c *Context) { c.String("user " + c.Param("id").(string)) })
+
+	r := New()
+	r.Use(func(c *Context) { trace += "main " })
+	admin := r.Group("/admin", func(c *Context) { trace += "group " })
+	admin.Mount("/v1", sub)
+
+	w := performRequest(r, "GET", "/admin/v1/users/7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user 7\n", w.Body.String())
+	assert.Equal(t, "main group sub ", trace)
+
+	trace = ""
+	w = performRequest(r, "GET", "/admin/v1/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "main group sub ", trace)
+}
+
+func TestMountRedirects(t *testing.T) {
+	sub := New()
+	sub.RedirectFixedPath = true
+	sub.Get("/users/:id", func(c *Context) {})
+
+	r := New()
+	r.Mount("/admin", sub)
+	r.Group("/api").Mount("/v1", sub)
+
+	tests := map[string]string{
+		"/admin/users/7/":          "/admin/users/7",
+		"/admin//users/../users/7": "/admin/users/7",
+		"/admin/users/7/?x=1":      "/admin/users/7?x=1",
+		"/api/v1/users/7/":         "/api/v1/users/7",
+	}
+	for path, location := range tests {
+		w := performRequest(r, "GET", path)
+		assert.Equal(t, http.StatusMovedPermanently, w.Code, path)
+		assert.Equal(t, location, w.Header().Get("Location"), path)
+
+		w = performRequest(r, "GET", location)
+		assert.Equal(t, http.StatusOK, w.Code, location)
+	}
+}